@@ -0,0 +1,269 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ErrNoSuchField is reported by the accessors of Record for missing fields.
+var ErrNoSuchField = errors.New("No such field")
+
+// getField stores the field code of rec in a T as UnmarshalRecord does.
+func getField[T any](rec Record, code string) (T, error) {
+	var v T
+	f, ok := rec.Fields[code]
+	if !ok {
+		return v, &MappingError{code, "", reflect.TypeOf(v), ErrNoSuchField}
+	}
+	rv := reflect.ValueOf(&v).Elem()
+	if err := unmarshalField(f, rv); err != nil {
+		if _, ok := err.(*MappingError); ok {
+			return v, err
+		}
+		return v, &MappingError{code, fieldTypeOf(f), rv.Type(), err}
+	}
+	return v, nil
+}
+
+// String returns the value of a text, number, selection, link, date,
+// time or status field.  Empty selections are returned as "".
+func (rec Record) String(code string) (string, error) {
+	return getField[string](rec, code)
+}
+
+// Decimal returns the value of a number or calculated field.
+// nil is returned if the field is empty.
+func (rec Record) Decimal(code string) (*big.Rat, error) {
+	s, err := rec.String(code)
+	if err != nil || len(s) == 0 {
+		return nil, err
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		f := rec.Fields[code]
+		return nil, &MappingError{code, fieldTypeOf(f), reflect.TypeOf(r), errors.New("not a number: " + s)}
+	}
+	return r, nil
+}
+
+// Float is like Decimal but returns the value as float64.
+// 0 is returned if the field is empty.
+func (rec Record) Float(code string) (float64, error) {
+	return getField[float64](rec, code)
+}
+
+// Int is like Decimal but returns the value as int64.
+// 0 is returned if the field is empty.
+func (rec Record) Int(code string) (int64, error) {
+	return getField[int64](rec, code)
+}
+
+// Date returns the value of a date field.
+// The zero time is returned if the field is empty.
+func (rec Record) Date(code string) (time.Time, error) {
+	switch f := rec.Fields[code].(type) {
+	case nil, DateField:
+	default:
+		return time.Time{}, timeMismatch(code, f, "date")
+	}
+	return getField[time.Time](rec, code)
+}
+
+// Time returns the value of a time field.
+// The zero time is returned if the field is empty.
+func (rec Record) Time(code string) (time.Time, error) {
+	switch f := rec.Fields[code].(type) {
+	case nil, TimeField:
+	default:
+		return time.Time{}, timeMismatch(code, f, "time")
+	}
+	return getField[time.Time](rec, code)
+}
+
+// DateTime returns the value of a datetime, created time or updated
+// time field.  The zero time is returned if the field is empty.
+func (rec Record) DateTime(code string) (time.Time, error) {
+	switch f := rec.Fields[code].(type) {
+	case nil, DateTimeField, CreationTimeField, ModificationTimeField:
+	default:
+		return time.Time{}, timeMismatch(code, f, "datetime")
+	}
+	return getField[time.Time](rec, code)
+}
+
+// timeMismatch reports that the field f is not of the kind of time
+// expected by an accessor.
+func timeMismatch(code string, f interface{}, kind string) error {
+	return &MappingError{code, fieldTypeOf(f), reflect.TypeOf(time.Time{}), errors.New("not a " + kind + " field")}
+}
+
+// Strings returns the values of a check box, multi-select or category field.
+func (rec Record) Strings(code string) ([]string, error) {
+	return getField[[]string](rec, code)
+}
+
+// Users returns the users of a user selection or assignee field.
+func (rec Record) Users(code string) ([]User, error) {
+	return getField[[]User](rec, code)
+}
+
+// User returns the user of a created by or updated by field.
+func (rec Record) User(code string) (User, error) {
+	return getField[User](rec, code)
+}
+
+// Organizations returns the departments of a department selection field.
+func (rec Record) Organizations(code string) ([]Organization, error) {
+	return getField[[]Organization](rec, code)
+}
+
+// Groups returns the groups of a group selection field.
+func (rec Record) Groups(code string) ([]Group, error) {
+	return getField[[]Group](rec, code)
+}
+
+// File returns the files of an attachment field.
+func (rec Record) File(code string) ([]File, error) {
+	return getField[[]File](rec, code)
+}
+
+// SubTable returns the rows of a subtable field.
+func (rec Record) SubTable(code string) ([]*Record, error) {
+	st, err := getField[SubTableField](rec, code)
+	return []*Record(st), err
+}
+
+// setField sets v to the field code of rec.  The type of an existing
+// field is kept, otherwise the field is created with type ft.
+func (rec *Record) setField(code string, v interface{}, ft string) error {
+	if old, ok := rec.Fields[code]; ok {
+		if t := fieldTypeOf(old); len(t) > 0 {
+			ft = t
+		}
+	}
+	f, err := marshalField(reflect.ValueOf(v), ft)
+	if err != nil {
+		return &MappingError{code, ft, reflect.TypeOf(v), err}
+	}
+	if rec.Fields == nil {
+		rec.Fields = make(map[string]interface{})
+	}
+	rec.Fields[code] = f
+	return nil
+}
+
+// SetString sets s to a field.  A new field is a single-line text field.
+func (rec *Record) SetString(code string, s string) error {
+	return rec.setField(code, s, FT_SINGLE_LINE_TEXT)
+}
+
+// SetDecimal sets r to a field.  A new field is a number field.
+// nil empties the field.  *MappingError is returned if r has no exact
+// decimal form, like 1/3; round it with FloatString and use SetString.
+func (rec *Record) SetDecimal(code string, r *big.Rat) error {
+	s := ""
+	if r != nil {
+		prec, ok := decimalPrec(r)
+		if !ok {
+			return &MappingError{code, fieldTypeOf(rec.Fields[code]), reflect.TypeOf(r), errors.New("no exact decimal form: " + r.String())}
+		}
+		s = r.FloatString(prec)
+	}
+	return rec.setField(code, s, FT_DECIMAL)
+}
+
+// decimalPrec returns the number of digits after the decimal point
+// required to write r exactly.  ok is false if r has no exact decimal
+// form, i.e. its denominator has prime factors other than 2 and 5.
+func decimalPrec(r *big.Rat) (prec int, ok bool) {
+	d := new(big.Int).Set(r.Denom())
+	m := new(big.Int)
+	count := func(p int64) int {
+		n := 0
+		bp := big.NewInt(p)
+		for {
+			q, _ := new(big.Int).QuoRem(d, bp, m)
+			if m.Sign() != 0 {
+				return n
+			}
+			d, n = q, n+1
+		}
+	}
+	twos, fives := count(2), count(5)
+	return max(twos, fives), d.Cmp(big.NewInt(1)) == 0
+}
+
+// SetFloat sets f to a field.  A new field is a number field.
+func (rec *Record) SetFloat(code string, f float64) error {
+	return rec.setField(code, strconv.FormatFloat(f, 'f', -1, 64), FT_DECIMAL)
+}
+
+// SetInt sets n to a field.  A new field is a number field.
+func (rec *Record) SetInt(code string, n int64) error {
+	return rec.setField(code, strconv.FormatInt(n, 10), FT_DECIMAL)
+}
+
+// SetDate sets the date of t to a field.  A new field is a date field.
+// The zero time empties the field.
+func (rec *Record) SetDate(code string, t time.Time) error {
+	return rec.setField(code, t, FT_DATE)
+}
+
+// SetTime sets the time of t to a field.  A new field is a time field.
+// The zero time empties the field.
+func (rec *Record) SetTime(code string, t time.Time) error {
+	return rec.setField(code, t, FT_TIME)
+}
+
+// SetDateTime sets t to a field.  A new field is a datetime field.
+// The zero time empties the field.
+func (rec *Record) SetDateTime(code string, t time.Time) error {
+	return rec.setField(code, t, FT_DATETIME)
+}
+
+// SetStrings sets sl to a field.  A new field is a check box field.
+func (rec *Record) SetStrings(code string, sl []string) error {
+	return rec.setField(code, sl, FT_CHECK_BOX)
+}
+
+// SetUsers sets ul to a field.  A new field is a user selection field.
+func (rec *Record) SetUsers(code string, ul []User) error {
+	return rec.setField(code, ul, FT_USER)
+}
+
+// SetOrganizations sets ol to a field.  A new field is a department
+// selection field.
+func (rec *Record) SetOrganizations(code string, ol []Organization) error {
+	return rec.setField(code, ol, FT_ORGANIZATION)
+}
+
+// SetGroups sets gl to a field.  A new field is a group selection field.
+func (rec *Record) SetGroups(code string, gl []Group) error {
+	return rec.setField(code, gl, FT_GROUP)
+}
+
+// SetFile sets fl to an attachment field.
+func (rec *Record) SetFile(code string, fl []File) error {
+	return rec.setField(code, fl, FT_FILE)
+}
+
+// SetSubTable sets rows to a subtable field.
+func (rec *Record) SetSubTable(code string, rows []*Record) error {
+	if old, ok := rec.Fields[code]; ok {
+		if _, ok := old.(SubTableField); !ok {
+			return &MappingError{code, fieldTypeOf(old), reflect.TypeOf(rows), errors.New("not a subtable")}
+		}
+	}
+	if rec.Fields == nil {
+		rec.Fields = make(map[string]interface{})
+	}
+	rec.Fields[code] = SubTableField(rows)
+	return nil
+}
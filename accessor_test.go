@@ -0,0 +1,172 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordAccessors(t *testing.T) {
+	t.Parallel()
+
+	rec := NewRecord(map[string]interface{}{
+		"title":  SingleLineTextField("hoge"),
+		"price":  DecimalField("12.50"),
+		"empty":  DecimalField(""),
+		"choice": SingleSelectField{Valid: false},
+		"date":   NewDateField(2019, time.March, 11),
+		"dt":     DateTimeField{Valid: false},
+		"ctime":  CreationTimeField(time.Date(2012, 2, 3, 8, 50, 0, 0, time.UTC)),
+		"tags":   CategoryField{"a", "b"},
+		"users":  AssigneeField{{"sato", "Noboru Sato"}},
+		"owner":  CreatorField{"sato", "Noboru Sato"},
+		"file":   FileField{{"text/plain", "abc", "a.txt", 12}},
+		"table":  SubTableField{NewRecordWithId(10, map[string]interface{}{"qty": DecimalField("2")})},
+	})
+
+	if s, err := rec.String("title"); err != nil || s != "hoge" {
+		t.Errorf("String: %q %v", s, err)
+	}
+	if s, err := rec.String("choice"); err != nil || s != "" {
+		t.Errorf("String: %q %v", s, err)
+	}
+	if r, err := rec.Decimal("price"); err != nil || r.Cmp(big.NewRat(25, 2)) != 0 {
+		t.Errorf("Decimal: %v %v", r, err)
+	}
+	if r, err := rec.Decimal("empty"); err != nil || r != nil {
+		t.Errorf("Decimal: %v %v", r, err)
+	}
+	if f, err := rec.Float("price"); err != nil || f != 12.5 {
+		t.Errorf("Float: %v %v", f, err)
+	}
+	if _, err := rec.Int("price"); err == nil {
+		t.Error("Int must fail for decimals")
+	}
+	if d, err := rec.Date("date"); err != nil || !d.Equal(time.Date(2019, 3, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Date: %v %v", d, err)
+	}
+	if d, err := rec.DateTime("dt"); err != nil || !d.IsZero() {
+		t.Errorf("DateTime: %v %v", d, err)
+	}
+	if d, err := rec.DateTime("ctime"); err != nil || d.Year() != 2012 {
+		t.Errorf("DateTime: %v %v", d, err)
+	}
+	if sl, err := rec.Strings("tags"); err != nil || !reflect.DeepEqual(sl, []string{"a", "b"}) {
+		t.Errorf("Strings: %v %v", sl, err)
+	}
+	if ul, err := rec.Users("users"); err != nil || len(ul) != 1 || ul[0].Code != "sato" {
+		t.Errorf("Users: %v %v", ul, err)
+	}
+	if u, err := rec.User("owner"); err != nil || u.Name != "Noboru Sato" {
+		t.Errorf("User: %v %v", u, err)
+	}
+	if fl, err := rec.File("file"); err != nil || len(fl) != 1 || fl[0].Size != 12 {
+		t.Errorf("File: %v %v", fl, err)
+	}
+	if rows, err := rec.SubTable("table"); err != nil || len(rows) != 1 || rows[0].Id() != 10 {
+		t.Errorf("SubTable: %v %v", rows, err)
+	}
+
+	_, err := rec.Strings("title")
+	var me *MappingError
+	if !errors.As(err, &me) || me.FieldCode != "title" || me.FieldType != FT_SINGLE_LINE_TEXT {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := rec.String("missing"); !errors.Is(err, ErrNoSuchField) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := rec.Date("title"); err == nil {
+		t.Error("Date must fail for texts")
+	}
+	for _, get := range []func(string) (time.Time, error){rec.Date, rec.Time} {
+		if _, err := get("ctime"); !errors.As(err, &me) || me.FieldCode != "ctime" || me.FieldType != FT_CTIME {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if _, err := rec.DateTime("date"); !errors.As(err, &me) || me.FieldCode != "date" || me.FieldType != FT_DATE {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRecordSetters(t *testing.T) {
+	t.Parallel()
+
+	rec := NewRecord(map[string]interface{}{
+		"radio": RadioButtonField("a"),
+		"multi": MultiSelectField{"a"},
+		"tags":  CheckBoxField{"a"},
+	})
+	for _, err := range []error{
+		rec.SetString("title", "hoge"),
+		rec.SetString("radio", "b"),
+		rec.SetDecimal("price", big.NewRat(3, 2)),
+		rec.SetDecimal("empty", nil),
+		rec.SetInt("count", 5),
+		rec.SetFloat("ratio", 0.25),
+		rec.SetDate("date", time.Date(2019, 3, 11, 10, 0, 0, 0, time.UTC)),
+		rec.SetTime("time", time.Date(0, 1, 1, 9, 53, 0, 0, time.UTC)),
+		rec.SetDateTime("dt", time.Time{}),
+		rec.SetStrings("multi", []string{"b", "c"}),
+		rec.SetUsers("users", []User{{"sato", ""}}),
+		rec.SetOrganizations("orgs", []Organization{{"sales", ""}}),
+		rec.SetGroups("groups", []Group{{"managers", ""}}),
+		rec.SetFile("file", []File{{FileKey: "abc"}}),
+		rec.SetSubTable("table", []*Record{NewRecord(nil)}),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := map[string]interface{}{
+		"title":  SingleLineTextField("hoge"),
+		"radio":  RadioButtonField("b"),
+		"price":  DecimalField("1.5"),
+		"empty":  DecimalField(""),
+		"count":  DecimalField("5"),
+		"ratio":  DecimalField("0.25"),
+		"date":   NewDateField(2019, time.March, 11),
+		"time":   TimeField{time.Date(0, 1, 1, 9, 53, 0, 0, time.UTC), true},
+		"dt":     DateTimeField{Valid: false},
+		"multi":  MultiSelectField{"b", "c"},
+		"tags":   CheckBoxField{"a"},
+		"users":  UserField{{"sato", ""}},
+		"orgs":   OrganizationField{{"sales", ""}},
+		"groups": GroupField{{"managers", ""}},
+		"file":   FileField{{FileKey: "abc"}},
+		"table":  SubTableField{NewRecord(nil)},
+	}
+	for code, f := range expected {
+		if !reflect.DeepEqual(rec.Fields[code], f) {
+			t.Errorf("%s: %#v", code, rec.Fields[code])
+		}
+	}
+
+	if err := rec.SetString("tags", "x"); err == nil {
+		t.Error("SetString must fail for check boxes")
+	}
+	if err := rec.SetSubTable("title", nil); err == nil {
+		t.Error("SetSubTable must fail for texts")
+	}
+
+	tiny, _ := new(big.Rat).SetString("1e-21")
+	for r, expected := range map[*big.Rat]DecimalField{
+		tiny:               "0.000000000000000000001",
+		big.NewRat(-7, 4):  "-1.75",
+		big.NewRat(100, 1): "100",
+	} {
+		if err := rec.SetDecimal("price", r); err != nil || rec.Fields["price"] != expected {
+			t.Errorf("unexpected decimal: %v %v", rec.Fields["price"], err)
+		}
+	}
+	var me *MappingError
+	old := rec.Fields["price"]
+	if err := rec.SetDecimal("price", big.NewRat(1, 3)); !errors.As(err, &me) || rec.Fields["price"] != old {
+		t.Errorf("SetDecimal must fail without an exact decimal form: %v", err)
+	}
+}
@@ -6,12 +6,14 @@ package kintone
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log/slog"
 	"mime"
 	"mime/multipart"
 	"net/http"
@@ -34,16 +36,22 @@ var (
 	ErrTimeout         = errors.New("Timeout")
 	ErrInvalidResponse = errors.New("Invalid Response")
 	ErrTooMany         = errors.New("Too many records")
+	ErrInvalidKeyField = errors.New("Invalid key field")
 )
 
 // Server-side errors.
+//
+// Use errors.Is with ErrPermissionDenied, ErrRevisionConflict,
+// ErrRecordNotFound, ErrInvalidQuery or ErrThrottled to classify them.
 type AppError struct {
-	HttpStatus     string `json:"-"`       // e.g. "404 NotFound"
-	HttpStatusCode int    `json:"-"`       // e.g. 404
-	Message        string `json:"message"` // Human readable message.
-	Id             string `json:"id"`      // A unique error ID.
-	Code           string `json:"code"`    // For machines.
-	Errors         string `json:"errors"`  // Error Description.
+	HttpStatus     string                 `json:"-"`       // e.g. "404 NotFound"
+	HttpStatusCode int                    `json:"-"`       // e.g. 404
+	Message        string                 `json:"message"` // Human readable message.
+	Id             string                 `json:"id"`      // A unique error ID.
+	Code           string                 `json:"code"`    // For machines.
+	Errors         string                 `json:"errors"`  // Error Description.
+	FieldErrors    map[string]*FieldError `json:"-"`       // Errors property keyed by the path.
+	results        []json.RawMessage      // Results property of bulkRequest errors.
 }
 
 type AppFormFields struct {
@@ -114,10 +122,26 @@ func (f UpdateKey) MarshalJSON() ([]byte, error) {
 //		...
 //	}
 //
+// An App is safe for concurrent use by multiple goroutines as long as
+// its fields are not modified, including through SetBasicAuth and
+// SetUserAgentHeader, while API methods are running.  NewApp validates
+// the configuration and returns such an instance.  An App built as a
+// struct literal works the same but shares an *http.Client without a
+// cookie jar with other such instances when Client is nil.
+//
+// Every API method has a variant suffixed with Context, such as
+// GetRecordsContext, that takes a context.Context.  Canceling the
+// context aborts the in-flight HTTP request.  Timeout still bounds the
+// time to wait for each response regardless of the context.
+//
 // Errors returned by the methods of App may be one of *AppError,
-// ErrTimeout, ErrInvalidResponse, or ErrTooMany.
+// ErrTimeout, ErrInvalidResponse, ErrTooMany, ErrQuotaExceeded, or the error of the
+// context passed to a Context variant.  Records which could not be decoded
+// are reported by *FieldDecodeError, or by *DecodeError along with the
+// partially decoded records if LenientDecoding is set.
 type App struct {
 	Domain            string        // domain name.  ex: "sample.cybozu.com", "sample.kintone.com", "sample.cybozu.cn"
+	BaseURL           string        // Base URL to use instead of "https://" + Domain.  ex: "http://localhost:8080/proxy"
 	User              string        // User account for API.
 	Password          string        // User password for API.
 	AppId             uint64        // application ID.
@@ -125,11 +149,88 @@ type App struct {
 	Timeout           time.Duration // Timeout for API responses.
 	ApiToken          string        // API token.
 	GuestSpaceId      uint64        // guest space ID.
-	token             string        // auth token.
+	Retry             *RetryPolicy  // Retry policy for transient failures.  nil disables retries.
+	Limiter           *Limiter      // Limiter shared by the apps of the domain.  nil disables limiting.
+	Middlewares       []Middleware  // Middlewares applied to every API call.  The first is the outermost.
+	Logger            *slog.Logger  // Logger for API calls.  nil disables logging.
+	LenientDecoding   bool          // Return partially decoded records with *DecodeError.
+	SendAllFields     bool          // Send every field in updates instead of changed writable ones.
+	SyncRecords       bool          // Store IDs and revisions returned by writes in the passed records.
 	basicAuth         bool          // true to use Basic Authentication.
 	basicAuthUser     string        // User name for Basic Authentication.
 	basicAuthPassword string        // Password for Basic Authentication.
 	extUserAgent      string        // User-agent request header string
+	keyFields         *keyCache     // Valid key fields of upserts.
+}
+
+// defaultClient is used by App instances whose Client is nil.
+var defaultClient = &http.Client{}
+
+// NewApp validates config and returns a new App configured with it.
+//
+// Unset Client and Timeout are filled with a new *http.Client that has
+// its own cookie jar and DEFAULT_TIMEOUT respectively.
+func NewApp(config App) (*App, error) {
+	err := validateConfig(config.Domain, config.BaseURL, config.User, config.Password, config.ApiToken, config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	app := config
+	if app.Client == nil {
+		if app.Client, err = newHTTPClient(); err != nil {
+			return nil, err
+		}
+	}
+	if app.Timeout == time.Duration(0) {
+		app.Timeout = DEFAULT_TIMEOUT
+	}
+	app.keyFields = newKeyCache()
+	return &app, nil
+}
+
+func validateConfig(domain, baseURL, user, password, apiToken string, timeout time.Duration) error {
+	if len(domain) == 0 && len(baseURL) == 0 {
+		return errors.New("Domain or BaseURL is required")
+	}
+	if len(baseURL) > 0 {
+		if _, err := parseBaseURL(baseURL); err != nil {
+			return err
+		}
+	}
+	if len(user) > 0 && len(password) == 0 {
+		return errors.New("Password is required for User")
+	}
+	if len(user) == 0 && len(apiToken) == 0 {
+		return errors.New("User or ApiToken is required")
+	}
+	if timeout < 0 {
+		return errors.New("Timeout must not be negative")
+	}
+	return nil
+}
+
+// newHTTPClient returns an *http.Client with its own cookie jar.
+func newHTTPClient() (*http.Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Jar: jar}, nil
+}
+
+func (app *App) httpClient() *http.Client {
+	if app.Client == nil {
+		return defaultClient
+	}
+	return app.Client
+}
+
+func (app *App) timeout() time.Duration {
+	if app.Timeout == time.Duration(0) {
+		return DEFAULT_TIMEOUT
+	}
+	return app.Timeout
 }
 
 // SetBasicAuth enables use of HTTP basic authentication for access
@@ -169,22 +270,48 @@ func (app *App) GetUserAgentHeader() string {
 	return userAgent
 }
 
-func (app *App) createUrl(api string, query string) url.URL {
-	path := fmt.Sprintf("/k/v1/%s.json", api)
-	if app.GuestSpaceId > 0 {
-		path = fmt.Sprintf("/k/guest/%d/v1/%s.json", app.GuestSpaceId, api)
-	}
-
-	resultUrl := url.URL{
+// endpoint returns the URL of path on the kintone server.
+//
+// The URL is based on BaseURL if set, or "https://" + Domain otherwise.
+func (app *App) endpoint(path, query string) (*url.URL, error) {
+	u := &url.URL{
 		Scheme: "https",
 		Host:   app.Domain,
-		Path:   path,
 	}
+	if len(app.BaseURL) > 0 {
+		base, err := parseBaseURL(app.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+		u = base
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = query
+	return u, nil
+}
 
-	if len(query) > 0 {
-		resultUrl.RawQuery = query
+// apiURL returns the URL of a kintone REST API such as "records".
+func (app *App) apiURL(api, query string) (*url.URL, error) {
+	return app.endpoint(app.apiPath(api), query)
+}
+
+// apiPath returns the path of api such as "/k/v1/records.json".
+func (app *App) apiPath(api string) string {
+	if app.GuestSpaceId > 0 {
+		return fmt.Sprintf("/k/guest/%d/v1/%s.json", app.GuestSpaceId, api)
 	}
-	return resultUrl
+	return fmt.Sprintf("/k/v1/%s.json", api)
+}
+
+func parseBaseURL(baseURL string) (*url.URL, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return nil, fmt.Errorf("Invalid BaseURL: %s", baseURL)
+	}
+	return u, nil
 }
 
 func (app *App) setAuth(request *http.Request) {
@@ -194,29 +321,31 @@ func (app *App) setAuth(request *http.Request) {
 
 	if len(app.ApiToken) > 0 {
 		request.Header.Set("X-Cybozu-API-Token", app.ApiToken)
-	}
-
-	if len(app.User) > 0 && len(app.Password) > 0 {
+	} else if len(app.User) > 0 {
 		request.Header.Set("X-Cybozu-Authorization", base64.StdEncoding.EncodeToString(
 			[]byte(app.User+":"+app.Password)))
 	}
 }
 
 // NewRequest create a request connect to kintone api.
+//
+// url should be built for the server of app, for instance
+// "https://example.cybozu.com/k/v1/records.json".
 func (app *App) NewRequest(method, url string, body io.Reader) (*http.Request, error) {
-	bodyData := io.Reader(nil)
-	if body != nil {
-		bodyData = body
-	}
+	return app.NewRequestWithContext(context.Background(), method, url, body)
+}
 
-	request, err := http.NewRequest(method, url, bodyData)
+// NewRequestWithContext is like NewRequest but binds the request to ctx.
+func (app *App) NewRequestWithContext(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
 
 	request.Header.Set("User-Agent", app.GetUserAgentHeader())
 
-	if method != "GET" {
+	// kintone requires Content-Type even for GET requests with a body.
+	if body != nil || method != "GET" {
 		request.Header.Set("Content-Type", "application/json")
 	}
 
@@ -225,84 +354,97 @@ func (app *App) NewRequest(method, url string, body io.Reader) (*http.Request, e
 	return request, nil
 }
 
-func (app *App) newRequest(method, api string, body io.Reader) (*http.Request, error) {
-	if len(app.token) == 0 {
-		app.token = base64.StdEncoding.EncodeToString(
-			[]byte(app.User + ":" + app.Password))
-	}
-
-	var path string
-	if app.GuestSpaceId == 0 {
-		path = fmt.Sprintf("/k/v1/%s.json", api)
-	} else {
-		path = fmt.Sprintf("/k/guest/%d/v1/%s.json", app.GuestSpaceId, api)
-	}
-
-	u := url.URL{
-		Scheme: "https",
-		Host:   app.Domain,
-		Path:   path,
-	}
-	req, err := http.NewRequest(method, u.String(), body)
+// newRequest creates a request for a kintone REST API such as "records".
+func (app *App) newRequest(ctx context.Context, method, api, query string, body io.Reader) (*http.Request, error) {
+	u, err := app.apiURL(api, query)
 	if err != nil {
 		return nil, err
 	}
-	if app.basicAuth {
-		req.SetBasicAuth(app.basicAuthUser, app.basicAuthPassword)
-	}
-	if len(app.ApiToken) == 0 {
-		req.Header.Set("X-Cybozu-Authorization", app.token)
-	} else {
-		req.Header.Set("X-Cybozu-API-Token", app.ApiToken)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", app.GetUserAgentHeader())
+	return app.NewRequestWithContext(ctx, method, u.String(), body)
+}
 
-	return req, nil
+// releaseOnClose releases the resources of a request, such as its
+// context and limiter slot, when its response body is closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
 }
 
+// do sends req and waits for the response headers, retrying as
+// app.Retry allows.
 func (app *App) do(req *http.Request) (*http.Response, error) {
-	if app.Client == nil {
-		jar, err := cookiejar.New(nil)
-		if err != nil {
-			return nil, err
+	for attempt := 1; ; attempt++ {
+		resp, err := app.send(req)
+		delay, retry := app.Retry.check(req, resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+		app.logRetry(req, resp, err, attempt, delay)
+		if err == nil {
+			resp.Body.Close()
+		}
+
+		ctx := req.Context()
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
 		}
-		app.Client = &http.Client{Jar: jar}
-	}
-	if app.Timeout == time.Duration(0) {
-		app.Timeout = DEFAULT_TIMEOUT
-	}
 
-	type result struct {
-		resp *http.Response
-		err  error
+		req = req.Clone(ctx)
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
 	}
-	done := make(chan result, 1)
-	go func() {
-		resp, err := app.Client.Do(req)
-		done <- result{resp, err}
-	}()
+}
 
-	type requestCanceler interface {
-		CancelRequest(*http.Request)
+// send sends req once and waits for the response headers.
+//
+// app.Timeout bounds the time to receive the response headers.  The
+// context of req, if canceled or past its deadline, aborts the request
+// at any time including while the response body is being read.
+//
+// If app.Limiter is set, send waits for its permission first and holds
+// it until the response body is closed.
+func (app *App) send(req *http.Request) (*http.Response, error) {
+	release, err := app.Limiter.acquire(req.Context(), app.AppId)
+	if err != nil {
+		return nil, err
 	}
 
-	select {
-	case r := <-done:
-		return r.resp, r.err
-	case <-time.After(app.Timeout):
-		if canceller, ok := app.Client.Transport.(requestCanceler); ok {
-			canceller.CancelRequest(req)
-		} else {
-			go func() {
-				r := <-done
-				if r.err == nil {
-					r.resp.Body.Close()
-				}
-			}()
+	ctx, cancel := context.WithCancel(req.Context())
+	done := func() {
+		cancel()
+		release()
+	}
+	timer := time.AfterFunc(app.timeout(), cancel)
+	app.logRequest(req)
+	resp, err := app.httpClient().Do(req.WithContext(ctx))
+	if !timer.Stop() && req.Context().Err() == nil {
+		// The timer fired before the response headers arrived.
+		done()
+		if err == nil {
+			resp.Body.Close()
 		}
 		return nil, ErrTimeout
 	}
+	if err != nil {
+		done()
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{resp.Body, done}
+	app.logResponse(req, resp)
+	return resp, nil
 }
 
 func isJSON(contentType string) bool {
@@ -313,6 +455,17 @@ func isJSON(contentType string) bool {
 	return mediatype == "application/json"
 }
 
+// peekBody reads the body of resp and replaces it with a copy so that
+// it can be read again.
+func peekBody(resp *http.Response) []byte {
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{bytes.NewReader(body), resp.Body}
+	return body
+}
+
 func parseResponse(resp *http.Response) ([]byte, error) {
 	body, err := ioutil.ReadAll(resp.Body)
 	resp.Body.Close()
@@ -327,58 +480,67 @@ func parseResponse(resp *http.Response) ([]byte, error) {
 				HttpStatusCode: resp.StatusCode,
 			}
 		}
-
-		// Get other than the Errors property
-		var ae AppError
-		json.Unmarshal(body, &ae)
-		ae.HttpStatus = resp.Status
-		ae.HttpStatusCode = resp.StatusCode
-
-		// Get the Errors property
-		var errors interface{}
-		json.Unmarshal(body, &errors)
-		msg := errors.(map[string]interface{})
-		v, ok := msg["errors"]
-		// If the Errors property exists
-		if ok {
-			result, err := json.Marshal(v)
-			if err != nil {
-				return nil, err
-			}
-			ae.Errors = string(result)
+		ae, err := decodeAppError(resp.Status, resp.StatusCode, body)
+		if err != nil {
+			return nil, err
 		}
-		return nil, &ae
+		return nil, ae
 	}
 	return body, nil
 }
 
+// decodeAppError decodes the JSON body of an error response.
+func decodeAppError(status string, statusCode int, body []byte) (*AppError, error) {
+	// Get other than the Errors property
+	var ae AppError
+	json.Unmarshal(body, &ae)
+	ae.HttpStatus = status
+	ae.HttpStatusCode = statusCode
+
+	// Get the Errors and the Results properties
+	var msg struct {
+		Errors  interface{}       `json:"errors"`
+		Results []json.RawMessage `json:"results"`
+	}
+	if json.Unmarshal(body, &msg) != nil {
+		return &ae, nil
+	}
+	// If the Errors property exists
+	if msg.Errors != nil {
+		result, err := json.Marshal(msg.Errors)
+		if err != nil {
+			return nil, err
+		}
+		ae.Errors = string(result)
+		ae.FieldErrors = parseFieldErrors(msg.Errors)
+	}
+	ae.results = msg.Results
+	return &ae, nil
+}
+
 // GetRecord fetches a record.
 func (app *App) GetRecord(id uint64) (*Record, error) {
+	return app.GetRecordContext(context.Background(), id)
+}
+
+// GetRecordContext is like GetRecord but uses ctx for the API request.
+func (app *App) GetRecordContext(ctx context.Context, id uint64) (*Record, error) {
 	type request_body struct {
 		App uint64 `json:"app,string"`
 		Id  uint64 `json:"id,string"`
 	}
-	data, _ := json.Marshal(request_body{app.AppId, id})
-	req, err := app.newRequest("GET", "record", bytes.NewReader(data))
+	body, err := app.call(ctx, "GET", "record", request_body{app.AppId, id})
 	if err != nil {
 		return nil, err
 	}
-	resp, err := app.do(req)
-	if err != nil {
-		return nil, err
-	}
-	body, err := parseResponse(resp)
-	if err != nil {
-		return nil, err
-	}
-	rec, err := DecodeRecord(body)
-	if err != nil {
-		return nil, ErrInvalidResponse
+	rec, err := app.decoder().DecodeRecord(body)
+	if rec == nil {
+		return nil, invalidResponse(err)
 	}
-	return rec, nil
+	return rec, err
 }
 
-func (app *App) getRecords(fields []string, query string, totalCount bool) ([]*Record, string, error) {
+func (app *App) getRecords(ctx context.Context, fields []string, query string, totalCount bool) ([]*Record, string, error) {
 	type request_body struct {
 		App        uint64   `json:"app,string"`
 		Fields     []string `json:"fields"`
@@ -386,26 +548,38 @@ func (app *App) getRecords(fields []string, query string, totalCount bool) ([]*R
 		TotalCount bool     `json:"totalCount"`
 	}
 
-	data, _ := json.Marshal(request_body{app.AppId, fields, query, totalCount})
-
-	req, err := app.newRequest("GET", "records", bytes.NewReader(data))
-	if err != nil {
-		return nil, "", err
-	}
-	resp, err := app.do(req)
-	if err != nil {
+	recs, rr, err := app.readRecords(ctx, &APICall{
+		API:    "records",
+		Method: "GET",
+		AppId:  app.AppId,
+		Body:   request_body{app.AppId, fields, query, totalCount},
+	})
+	if recs == nil {
 		return nil, "", err
 	}
-	body, err := parseResponse(resp)
+	return recs, rr.TotalCount(), err
+}
+
+// readRecords sends call and decodes the records in the response while
+// reading it.  Records are returned with *DecodeError in lenient mode.
+func (app *App) readRecords(ctx context.Context, call *APICall) ([]*Record, *RecordReader, error) {
+	call.stream = true
+	result, err := app.invoke(ctx, call)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
-	recs, respTotalCount, err := DecodeRecordsWithTotalCount(body)
+	defer result.Stream.Close()
 
-	if err != nil {
-		return nil, "", ErrInvalidResponse
+	rr := app.decoder().NewRecordReader(result.Stream)
+	recs := []*Record{}
+	for rr.Next() {
+		recs = append(recs, rr.Record())
 	}
-	return recs, respTotalCount, nil
+	err = rr.Err()
+	if _, ok := err.(*DecodeError); err != nil && !ok {
+		return nil, nil, err
+	}
+	return recs, rr, err
 }
 
 // GetRecords fetches records matching given conditions.
@@ -418,52 +592,61 @@ func (app *App) getRecords(fields []string, query string, totalCount bool) ([]*R
 // If fields is nil, all fields are retrieved.
 // See API specs how to construct query strings.
 func (app *App) GetRecords(fields []string, query string) ([]*Record, error) {
-	records, _, err := app.getRecords(fields, query, false)
+	return app.GetRecordsContext(context.Background(), fields, query)
+}
+
+// GetRecordsContext is like GetRecords but uses ctx for the API request.
+func (app *App) GetRecordsContext(ctx context.Context, fields []string, query string) ([]*Record, error) {
+	records, _, err := app.getRecords(ctx, fields, query, false)
 	return records, err
 }
 
 // GetRecordsWithTotalCount fetches records matching given conditions and returns totalCount of query result.
 func (app *App) GetRecordsWithTotalCount(fields []string, query string) ([]*Record, string, error) {
-	return app.getRecords(fields, query, true)
+	return app.GetRecordsWithTotalCountContext(context.Background(), fields, query)
 }
 
-// GetAllRecords fetches all records.
+// GetRecordsWithTotalCountContext is like GetRecordsWithTotalCount but uses ctx for the API request.
+func (app *App) GetRecordsWithTotalCountContext(ctx context.Context, fields []string, query string) ([]*Record, string, error) {
+	return app.getRecords(ctx, fields, query, true)
+}
+
+// GetAllRecords fetches all records in ascending order of the record ID.
 //
 // If fields is nil, all fields are retrieved.
+// See ScanRecords to filter records or to process them page by page.
 func (app *App) GetAllRecords(fields []string) ([]*Record, error) {
+	return app.GetAllRecordsContext(context.Background(), fields)
+}
+
+// GetAllRecordsContext is like GetAllRecords but uses ctx for the API requests.
+func (app *App) GetAllRecordsContext(ctx context.Context, fields []string) ([]*Record, error) {
 	recs := make([]*Record, 0, 100)
-	type request_body struct {
-		App    uint64   `json:"app,string"`
-		Fields []string `json:"fields"`
-		Query  string   `json:"query"`
-	}
-	for {
-		query := "limit 100"
-		if len(recs) > 0 {
-			query = fmt.Sprintf("limit 100 offset %v", len(recs))
-		}
-		data, _ := json.Marshal(request_body{app.AppId, fields, query})
-		req, err := app.newRequest("GET", "records", bytes.NewReader(data))
-		if err != nil {
-			return nil, err
-		}
-		resp, err := app.do(req)
-		if err != nil {
-			return nil, err
-		}
-		body, err := parseResponse(resp)
-		if err != nil {
-			return nil, err
-		}
-		r, err := DecodeRecords(body)
-		if err != nil {
-			return nil, ErrInvalidResponse
-		}
+	err := app.ScanRecordsContext(ctx, fields, "", func(r []*Record, _ ScanProgress) error {
 		recs = append(recs, r...)
-		if len(r) < 100 {
-			return recs, nil
-		}
+		return nil
+	})
+	if _, ok := err.(*DecodeError); ok {
+		return recs, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+// decoder returns the Decoder for records in the responses to app.
+func (app *App) decoder() *Decoder {
+	return &Decoder{Lenient: app.LenientDecoding, Logger: app.Logger}
+}
+
+// invalidResponse returns err if it reports fields which could not be
+// decoded, or ErrInvalidResponse.
+func invalidResponse(err error) error {
+	if _, ok := err.(*FieldDecodeError); ok {
+		return err
 	}
+	return ErrInvalidResponse
 }
 
 func isAllowedLang(allowedLangs []string, lang string) bool {
@@ -479,6 +662,11 @@ func isAllowedLang(allowedLangs []string, lang string) bool {
 // This method can only be used when App is initialized with password authentication
 // lang must be one of default, en, zh, ja, user
 func (app *App) GetProcess(lang string) (process *Process, err error) {
+	return app.GetProcessContext(context.Background(), lang)
+}
+
+// GetProcessContext is like GetProcess but uses ctx for the API request.
+func (app *App) GetProcessContext(ctx context.Context, lang string) (process *Process, err error) {
 	type request_body struct {
 		App  uint64 `json:"app,string"`
 		Lang string `json:"lang,string"`
@@ -492,16 +680,7 @@ func (app *App) GetProcess(lang string) (process *Process, err error) {
 		err = errors.New("Illegal language provided")
 		return
 	}
-	data, _ := json.Marshal(request_body{app.AppId, lang})
-	req, err := app.newRequest("GET", "app/status", bytes.NewReader(data))
-	if err != nil {
-		return
-	}
-	resp, err := app.do(req)
-	if err != nil {
-		return
-	}
-	body, err := parseResponse(resp)
+	body, err := app.call(ctx, "GET", "app/status", request_body{app.AppId, lang})
 	if err != nil {
 		return
 	}
@@ -522,45 +701,48 @@ type FileData struct {
 //
 // fileKey should be obtained from FileField (= []File).
 func (app *App) Download(fileKey string) (*FileData, error) {
+	return app.DownloadContext(context.Background(), fileKey)
+}
+
+// DownloadContext is like Download but uses ctx for the API request.
+//
+// Canceling ctx also aborts reading the returned file contents.
+func (app *App) DownloadContext(ctx context.Context, fileKey string) (*FileData, error) {
 	type request_body struct {
 		FileKey string `json:"fileKey"`
 	}
-	data, _ := json.Marshal(request_body{fileKey})
-	req, err := app.newRequest("GET", "file", bytes.NewReader(data))
-	if err != nil {
-		return nil, err
-	}
-	resp, err := app.do(req)
+	result, err := app.invoke(ctx, &APICall{
+		API:    "file",
+		Method: "GET",
+		AppId:  app.AppId,
+		Body:   request_body{fileKey},
+		stream: true,
+	})
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		if !isJSON(resp.Header.Get("Content-Type")) {
-			return nil, &AppError{
-				HttpStatus:     resp.Status,
-				HttpStatusCode: resp.StatusCode,
-			}
-		}
-		var ae AppError
-		body, _ := ioutil.ReadAll(resp.Body)
-		resp.Body.Close()
-		json.Unmarshal(body, &ae)
-		ae.HttpStatus = resp.Status
-		ae.HttpStatusCode = resp.StatusCode
-		return nil, &ae
-	}
 
 	pin, pout := io.Pipe()
+	done := make(chan struct{})
 	go func() {
-		_, err := io.Copy(pout, resp.Body)
-		resp.Body.Close()
+		_, err := io.Copy(pout, result.Stream)
+		result.Stream.Close()
+		close(done)
 		if err != nil {
 			pout.CloseWithError(err)
 		} else {
 			pout.Close()
 		}
 	}()
-	return &FileData{resp.Header.Get("Content-Type"), pin}, nil
+	go func() {
+		// Unblock the copy above even if nobody reads the contents.
+		select {
+		case <-ctx.Done():
+			pout.CloseWithError(ctx.Err())
+		case <-done:
+		}
+	}()
+	return &FileData{result.Header.Get("Content-Type"), pin}, nil
 }
 
 var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
@@ -573,6 +755,11 @@ func escapeQuotes(s string) string {
 //
 // If successfully uploaded, the key string of the uploaded file is returned.
 func (app *App) Upload(fileName, contentType string, data io.Reader) (key string, err error) {
+	return app.UploadContext(context.Background(), fileName, contentType, data)
+}
+
+// UploadContext is like Upload but uses ctx for the API request.
+func (app *App) UploadContext(ctx context.Context, fileName, contentType string, data io.Reader) (key string, err error) {
 	f, err := ioutil.TempFile("", "go-kintone-")
 	if err != nil {
 		return
@@ -598,16 +785,13 @@ func (app *App) Upload(fileName, contentType string, data io.Reader) (key string
 		return
 	}
 
-	req, err := app.newRequest("POST", "file", f)
-	if err != nil {
-		return
-	}
-	req.Header.Set("Content-Type", w.FormDataContentType())
-	resp, err := app.do(req)
-	if err != nil {
-		return
-	}
-	body, err := parseResponse(resp)
+	result, err := app.invoke(ctx, &APICall{
+		API:         "file",
+		Method:      "POST",
+		AppId:       app.AppId,
+		Body:        f,
+		ContentType: w.FormDataContentType(),
+	})
 	if err != nil {
 		return
 	}
@@ -615,7 +799,7 @@ func (app *App) Upload(fileName, contentType string, data io.Reader) (key string
 	var t struct {
 		FileKey string `json:"fileKey"`
 	}
-	if json.Unmarshal(body, &t) != nil {
+	if json.Unmarshal(result.Body, &t) != nil {
 		err = ErrInvalidResponse
 		return
 	}
@@ -626,20 +810,12 @@ func (app *App) Upload(fileName, contentType string, data io.Reader) (key string
 //
 // If successful, the record ID of the new record is returned.
 func (app *App) AddRecord(rec *Record) (id string, err error) {
-	type request_body struct {
-		App    uint64  `json:"app,string"`
-		Record *Record `json:"record"`
-	}
-	data, _ := json.Marshal(request_body{app.AppId, rec})
-	req, err := app.newRequest("POST", "record", bytes.NewReader(data))
-	if err != nil {
-		return
-	}
-	resp, err := app.do(req)
-	if err != nil {
-		return
-	}
-	body, err := parseResponse(resp)
+	return app.AddRecordContext(context.Background(), rec)
+}
+
+// AddRecordContext is like AddRecord but uses ctx for the API request.
+func (app *App) AddRecordContext(ctx context.Context, rec *Record) (id string, err error) {
+	body, err := app.addRecord(ctx, rec)
 	if err != nil {
 		return
 	}
@@ -651,33 +827,34 @@ func (app *App) AddRecord(rec *Record) (id string, err error) {
 		err = ErrInvalidResponse
 		return
 	}
+	if app.SyncRecords {
+		if _, err = app.addedResult(body, rec); err != nil {
+			return
+		}
+	}
 	id = t.Id
 	return
 }
 
+func (app *App) addRecord(ctx context.Context, rec *Record) ([]byte, error) {
+	type request_body struct {
+		App    uint64       `json:"app,string"`
+		Record recordFields `json:"record"`
+	}
+	return app.call(ctx, "POST", "record", request_body{app.AppId, rec.Fields})
+}
+
 // AddRecords adds new records.
 //
 // Up to 100 records can be added at once.
 // If successful, a list of record IDs is returned.
 func (app *App) AddRecords(recs []*Record) ([]string, error) {
-	if len(recs) > 100 {
-		return nil, ErrTooMany
-	}
+	return app.AddRecordsContext(context.Background(), recs)
+}
 
-	type request_body struct {
-		App     uint64    `json:"app,string"`
-		Records []*Record `json:"records"`
-	}
-	data, _ := json.Marshal(request_body{app.AppId, recs})
-	req, err := app.newRequest("POST", "records", bytes.NewReader(data))
-	if err != nil {
-		return nil, err
-	}
-	resp, err := app.do(req)
-	if err != nil {
-		return nil, err
-	}
-	body, err := parseResponse(resp)
+// AddRecordsContext is like AddRecords but uses ctx for the API request.
+func (app *App) AddRecordsContext(ctx context.Context, recs []*Record) ([]string, error) {
+	body, err := app.addRecords(ctx, recs)
 	if err != nil {
 		return nil, err
 	}
@@ -688,70 +865,148 @@ func (app *App) AddRecords(recs []*Record) ([]string, error) {
 	if json.Unmarshal(body, &t) != nil {
 		return nil, ErrInvalidResponse
 	}
+	if app.SyncRecords {
+		if _, err := app.addedResults(body, recs); err != nil {
+			return nil, err
+		}
+	}
 	return t.Ids, nil
 }
 
+func (app *App) addRecords(ctx context.Context, recs []*Record) ([]byte, error) {
+	if len(recs) > 100 {
+		return nil, ErrTooMany
+	}
+	return app.call(ctx, "POST", "records", addRecordsRequest(app.AppId, recs))
+}
+
+// addRecordsRequest returns the request body to add recs to the
+// application appId.
+func addRecordsRequest(appId uint64, recs []*Record) interface{} {
+	type request_body struct {
+		App     uint64         `json:"app,string"`
+		Records []recordFields `json:"records"`
+	}
+	t_recs := make([]recordFields, 0, len(recs))
+	for _, rec := range recs {
+		t_recs = append(t_recs, rec.Fields)
+	}
+	return request_body{appId, t_recs}
+}
+
+// updateFields returns the fields of rec sent by update requests.
+func (app *App) updateFields(rec *Record) recordFields {
+	if app.SendAllFields {
+		return rec.Fields
+	}
+	return rec.writableFields(true)
+}
+
 // UpdateRecord edits a record.
 //
 // If ignoreRevision is true, the record will always be updated despite
 // the revision number.  Else, the record may not be updated when the
 // same record was updated by another client.
+//
+// Built-in fields such as CalcField are never sent, and only fields
+// changed since rec was fetched are sent unless SendAllFields is set.
+// See Record.ChangedFields.
 func (app *App) UpdateRecord(rec *Record, ignoreRevision bool) error {
+	return app.UpdateRecordContext(context.Background(), rec, ignoreRevision)
+}
+
+// UpdateRecordContext is like UpdateRecord but uses ctx for the API request.
+func (app *App) UpdateRecordContext(ctx context.Context, rec *Record, ignoreRevision bool) error {
+	body, err := app.updateRecord(ctx, rec, ignoreRevision)
+	if err == nil && app.SyncRecords {
+		_, err = app.revisionResult(body, rec)
+	}
+	return err
+}
+
+func (app *App) updateRecord(ctx context.Context, rec *Record, ignoreRevision bool) ([]byte, error) {
 	type request_body struct {
-		App      uint64  `json:"app,string"`
-		Id       uint64  `json:"id,string"`
-		Revision int64   `json:"revision,string"`
-		Record   *Record `json:"record"`
+		App      uint64       `json:"app,string"`
+		Id       uint64       `json:"id,string"`
+		Revision int64        `json:"revision,string"`
+		Record   recordFields `json:"record"`
 	}
 	rev := rec.Revision()
 	if ignoreRevision {
 		rev = -1
 	}
-	data, _ := json.Marshal(request_body{app.AppId, rec.id, rev, rec})
-	req, err := app.newRequest("PUT", "record", bytes.NewReader(data))
+	body, err := app.call(ctx, "PUT", "record", request_body{app.AppId, rec.id, rev, app.updateFields(rec)})
 	if err != nil {
-		return err
-	}
-	resp, err := app.do(req)
-	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = parseResponse(resp)
-	return err
+	rec.takeSnapshot()
+	return body, nil
 }
 
 // UpdateRecordByKey edits a record by specified key field.
 func (app *App) UpdateRecordByKey(rec *Record, ignoreRevision bool, keyField string) error {
+	return app.UpdateRecordByKeyContext(context.Background(), rec, ignoreRevision, keyField)
+}
+
+// UpdateRecordByKeyContext is like UpdateRecordByKey but uses ctx for the API request.
+func (app *App) UpdateRecordByKeyContext(ctx context.Context, rec *Record, ignoreRevision bool, keyField string) error {
+	body, err := app.updateRecordByKey(ctx, rec, ignoreRevision, keyField)
+	if err == nil && app.SyncRecords {
+		_, err = app.revisionResult(body, rec)
+	}
+	return err
+}
+
+func (app *App) updateRecordByKey(ctx context.Context, rec *Record, ignoreRevision bool, keyField string) ([]byte, error) {
+	req, err := app.updateRecordByKeyRequest(rec, ignoreRevision, keyField, false)
+	if err != nil {
+		return nil, err
+	}
+	body, err := app.call(ctx, "PUT", "record", req)
+	if err != nil {
+		return nil, err
+	}
+	rec.takeSnapshot()
+	return body, nil
+}
+
+// updateRecordByKeyRequest returns the request body to update rec
+// identified by keyField, or to add it if upsert is true and no record
+// has the key.
+func (app *App) updateRecordByKeyRequest(rec *Record, ignoreRevision bool, keyField string, upsert bool) (interface{}, error) {
 	type request_body struct {
-		App       uint64    `json:"app,string"`
-		UpdateKey UpdateKey `json:"updateKey"`
-		Revision  int64     `json:"revision,string"`
-		Record    *Record   `json:"record"`
+		App       uint64       `json:"app,string"`
+		UpdateKey UpdateKey    `json:"updateKey"`
+		Revision  int64        `json:"revision,string"`
+		Record    recordFields `json:"record"`
+		Upsert    bool         `json:"upsert,omitempty"`
 	}
 	rev := rec.Revision()
 	if ignoreRevision {
 		rev = -1
 	}
-	updateKey := rec.Fields[keyField]
-	_rec := *rec
-	_rec.Fields = make(map[string]interface{})
-	for k, v := range rec.Fields {
-		if k != keyField {
-			_rec.Fields[k] = v
-		}
+	updateKey, fields, err := app.keyedFields(rec, keyField)
+	if err != nil {
+		return nil, err
 	}
-	data, _ := json.Marshal(request_body{app.AppId, UpdateKey{keyField, updateKey.(UpdateKeyField)}, rev, &_rec})
+	return request_body{app.AppId, updateKey, rev, fields, upsert}, nil
+}
 
-	req, err := app.newRequest("PUT", "record", bytes.NewReader(data))
-	if err != nil {
-		return err
+// keyedFields splits the fields of rec sent by update requests into the
+// key and the others.  ErrInvalidKeyField is returned if rec has no
+// value of keyField which can be a key.
+func (app *App) keyedFields(rec *Record, keyField string) (UpdateKey, recordFields, error) {
+	updateKey, ok := rec.Fields[keyField].(UpdateKeyField)
+	if !ok {
+		return UpdateKey{}, nil, fmt.Errorf("%w: record has no %s", ErrInvalidKeyField, keyField)
 	}
-	resp, err := app.do(req)
-	if err != nil {
-		return err
+	_rec := make(recordFields)
+	for k, v := range app.updateFields(rec) {
+		if k != keyField {
+			_rec[k] = v
+		}
 	}
-	_, err = parseResponse(resp)
-	return err
+	return UpdateKey{keyField, updateKey}, _rec, nil
 }
 
 // UpdateRecords edits multiple records at once.
@@ -759,14 +1014,40 @@ func (app *App) UpdateRecordByKey(rec *Record, ignoreRevision bool, keyField str
 // Up to 100 records can be edited at once.  ignoreRevision works the
 // same as UpdateRecord method.
 func (app *App) UpdateRecords(recs []*Record, ignoreRevision bool) error {
+	return app.UpdateRecordsContext(context.Background(), recs, ignoreRevision)
+}
+
+// UpdateRecordsContext is like UpdateRecords but uses ctx for the API request.
+func (app *App) UpdateRecordsContext(ctx context.Context, recs []*Record, ignoreRevision bool) error {
+	body, err := app.updateRecords(ctx, recs, ignoreRevision)
+	if err == nil && app.SyncRecords {
+		_, err = app.updatedResults(body, recs)
+	}
+	return err
+}
+
+func (app *App) updateRecords(ctx context.Context, recs []*Record, ignoreRevision bool) ([]byte, error) {
 	if len(recs) > 100 {
-		return ErrTooMany
+		return nil, ErrTooMany
 	}
 
+	body, err := app.call(ctx, "PUT", "records", app.updateRecordsRequest(app.AppId, recs, ignoreRevision))
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range recs {
+		rec.takeSnapshot()
+	}
+	return body, nil
+}
+
+// updateRecordsRequest returns the request body to update recs in the
+// application appId.
+func (app *App) updateRecordsRequest(appId uint64, recs []*Record, ignoreRevision bool) interface{} {
 	type update_t struct {
-		Id       uint64  `json:"id,string"`
-		Revision int64   `json:"revision,string"`
-		Record   *Record `json:"record"`
+		Id       uint64       `json:"id,string"`
+		Revision int64        `json:"revision,string"`
+		Record   recordFields `json:"record"`
 	}
 	type request_body struct {
 		App     uint64     `json:"app,string"`
@@ -778,67 +1059,90 @@ func (app *App) UpdateRecords(recs []*Record, ignoreRevision bool) error {
 		if ignoreRevision {
 			rev = -1
 		}
-		t_recs = append(t_recs, update_t{rec.Id(), rev, rec})
-	}
-	data, _ := json.Marshal(request_body{app.AppId, t_recs})
-	req, err := app.newRequest("PUT", "records", bytes.NewReader(data))
-	if err != nil {
-		return err
-	}
-	resp, err := app.do(req)
-	if err != nil {
-		return err
+		t_recs = append(t_recs, update_t{rec.Id(), rev, app.updateFields(rec)})
 	}
-	_, err = parseResponse(resp)
-	return err
+	return request_body{appId, t_recs}
 }
 
 // UpdateRecordsByKey edits multiple records by specified key fields at once.
 func (app *App) UpdateRecordsByKey(recs []*Record, ignoreRevision bool, keyField string) error {
+	return app.UpdateRecordsByKeyContext(context.Background(), recs, ignoreRevision, keyField)
+}
+
+// UpdateRecordsByKeyContext is like UpdateRecordsByKey but uses ctx for the API request.
+func (app *App) UpdateRecordsByKeyContext(ctx context.Context, recs []*Record, ignoreRevision bool, keyField string) error {
+	body, err := app.updateRecordsByKey(ctx, recs, ignoreRevision, keyField)
+	if err == nil && app.SyncRecords {
+		_, err = app.updatedResults(body, recs)
+	}
+	return err
+}
+
+func (app *App) updateRecordsByKey(ctx context.Context, recs []*Record, ignoreRevision bool, keyField string) ([]byte, error) {
 	if len(recs) > 100 {
-		return ErrTooMany
+		return nil, ErrTooMany
+	}
+
+	req, err := app.updateRecordsByKeyRequest(app.AppId, recs, ignoreRevision, keyField, false)
+	if err != nil {
+		return nil, err
+	}
+	body, err := app.call(ctx, "PUT", "records", req)
+	if err != nil {
+		return nil, err
 	}
+	for _, rec := range recs {
+		rec.takeSnapshot()
+	}
+	return body, nil
+}
 
+// updateRecordsByKeyRequest returns the request body to update recs
+// identified by keyField in the application appId.  If upsert is true,
+// records whose keys are not found are added.
+func (app *App) updateRecordsByKeyRequest(appId uint64, recs []*Record, ignoreRevision bool, keyField string, upsert bool) (interface{}, error) {
 	type update_t struct {
-		UpdateKey UpdateKey `json:"updateKey"`
-		Revision  int64     `json:"revision,string"`
-		Record    *Record   `json:"record"`
+		UpdateKey UpdateKey    `json:"updateKey"`
+		Revision  int64        `json:"revision,string"`
+		Record    recordFields `json:"record"`
 	}
 	type request_body struct {
 		App     uint64     `json:"app,string"`
 		Records []update_t `json:"records"`
+		Upsert  bool       `json:"upsert,omitempty"`
 	}
 	t_recs := make([]update_t, 0, len(recs))
-	for _, rec := range recs {
+	for i, rec := range recs {
 		rev := rec.Revision()
 		if ignoreRevision {
 			rev = -1
 		}
-		updateKey := rec.Fields[keyField]
-		_rec := *rec
-		_rec.Fields = make(map[string]interface{})
-		for k, v := range rec.Fields {
-			if k != keyField {
-				_rec.Fields[k] = v
-			}
+		updateKey, fields, err := app.keyedFields(rec, keyField)
+		if err != nil {
+			return nil, fmt.Errorf("records[%d]: %w", i, err)
 		}
-		t_recs = append(t_recs, update_t{UpdateKey{keyField, updateKey.(UpdateKeyField)}, rev, &_rec})
+		t_recs = append(t_recs, update_t{updateKey, rev, fields})
 	}
-	data, _ := json.Marshal(request_body{app.AppId, t_recs})
-	req, err := app.newRequest("PUT", "records", bytes.NewReader(data))
-	if err != nil {
-		return err
-	}
-	resp, err := app.do(req)
-	if err != nil {
-		return err
-	}
-	_, err = parseResponse(resp)
-	return err
+	return request_body{appId, t_recs, upsert}, nil
 }
 
 // UpdateRecordStatus updates the Status of a record
 func (app *App) UpdateRecordStatus(rec *Record, action *ProcessAction, assignee *Entity, ignoreRevision bool) (err error) {
+	return app.UpdateRecordStatusContext(context.Background(), rec, action, assignee, ignoreRevision)
+}
+
+// UpdateRecordStatusContext is like UpdateRecordStatus but uses ctx for the API request.
+func (app *App) UpdateRecordStatusContext(ctx context.Context, rec *Record, action *ProcessAction, assignee *Entity, ignoreRevision bool) (err error) {
+	body, err := app.call(ctx, "PUT", "record/status", updateRecordStatusRequest(app.AppId, rec, action, assignee, ignoreRevision))
+	if err == nil && app.SyncRecords {
+		_, err = app.revisionResult(body, rec)
+	}
+	return
+}
+
+// updateRecordStatusRequest returns the request body to take action on
+// rec in the application appId.
+func updateRecordStatusRequest(appId uint64, rec *Record, action *ProcessAction, assignee *Entity, ignoreRevision bool) interface{} {
 	type request_body struct {
 		App      uint64 `json:"app,string"`
 		Id       uint64 `json:"id,string"`
@@ -854,48 +1158,45 @@ func (app *App) UpdateRecordStatus(rec *Record, action *ProcessAction, assignee
 	if assignee != nil {
 		code = assignee.Code
 	}
-	data, _ := json.Marshal(request_body{app.AppId, rec.id, rev, action.Name, code})
-	req, err := app.newRequest("PUT", "record/status", bytes.NewReader(data))
-	if err != nil {
-		return
-	}
-	resp, err := app.do(req)
-	if err != nil {
-		return
-	}
-	_, err = parseResponse(resp)
-	return
+	return request_body{appId, rec.id, rev, action.Name, code}
 }
 
 // DeleteRecords deletes multiple records.
 //
 // Up to 100 records can be deleted at once.
 func (app *App) DeleteRecords(ids []uint64) error {
+	return app.DeleteRecordsContext(context.Background(), ids)
+}
+
+// DeleteRecordsContext is like DeleteRecords but uses ctx for the API request.
+func (app *App) DeleteRecordsContext(ctx context.Context, ids []uint64) error {
 	if len(ids) > 100 {
 		return ErrTooMany
 	}
 
+	_, err := app.call(ctx, "DELETE", "records", deleteRecordsRequest(app.AppId, ids))
+	return err
+}
+
+// deleteRecordsRequest returns the request body to delete records from
+// the application appId.
+func deleteRecordsRequest(appId uint64, ids []uint64) interface{} {
 	type request_body struct {
 		App uint64   `json:"app,string"`
 		Ids []uint64 `json:"ids,string"`
 	}
-	data, _ := json.Marshal(request_body{app.AppId, ids})
-	req, err := app.newRequest("DELETE", "records", bytes.NewReader(data))
-	if err != nil {
-		return err
-	}
-	resp, err := app.do(req)
-	if err != nil {
-		return err
-	}
-	_, err = parseResponse(resp)
-	return err
+	return request_body{appId, ids}
 }
 
 // GetRecordComments get comment list by record ID.
 //
 // It returns comment array.
 func (app *App) GetRecordComments(recordID uint64, order string, offset, limit uint64) ([]Comment, error) {
+	return app.GetRecordCommentsContext(context.Background(), recordID, order, offset, limit)
+}
+
+// GetRecordCommentsContext is like GetRecordComments but uses ctx for the API request.
+func (app *App) GetRecordCommentsContext(ctx context.Context, recordID uint64, order string, offset, limit uint64) ([]Comment, error) {
 	type requestBody struct {
 		App    uint64 `json:"app"`
 		Record uint64 `json:"record"`
@@ -904,19 +1205,10 @@ func (app *App) GetRecordComments(recordID uint64, order string, offset, limit u
 		Limit  uint64 `json:"limit"`
 	}
 
-	data, _ := json.Marshal(requestBody{app.AppId, recordID, order, offset, limit})
-	req, err := app.newRequest("GET", "record/comments", bytes.NewReader(data))
+	body, err := app.call(ctx, "GET", "record/comments", requestBody{app.AppId, recordID, order, offset, limit})
 	if err != nil {
 		return nil, err
 	}
-	resp, err := app.do(req)
-	if err != nil {
-		return nil, err
-	}
-	body, err := parseResponse(resp)
-	if err != nil {
-		return nil, ErrInvalidResponse
-	}
 	recs, err := DecodeRecordComments(body)
 	if err != nil {
 		return nil, err
@@ -929,21 +1221,17 @@ func (app *App) GetRecordComments(recordID uint64, order string, offset, limit u
 //
 // If successful, it returns the target record ID.
 func (app *App) AddRecordComment(recordId uint64, comment *Comment) (id string, err error) {
+	return app.AddRecordCommentContext(context.Background(), recordId, comment)
+}
+
+// AddRecordCommentContext is like AddRecordComment but uses ctx for the API request.
+func (app *App) AddRecordCommentContext(ctx context.Context, recordId uint64, comment *Comment) (id string, err error) {
 	type requestBody struct {
 		App     uint64   `json:"app,string"`
 		Record  uint64   `json:"record,string"`
 		Comment *Comment `json:"comment"`
 	}
-	data, _ := json.Marshal(requestBody{app.AppId, recordId, comment})
-	req, err := app.newRequest("POST", "record/comment", bytes.NewReader(data))
-	if err != nil {
-		return
-	}
-	resp, err := app.do(req)
-	if err != nil {
-		return
-	}
-	body, err := parseResponse(resp)
+	body, err := app.call(ctx, "POST", "record/comment", requestBody{app.AppId, recordId, comment})
 	if err != nil {
 		return
 	}
@@ -960,23 +1248,18 @@ func (app *App) AddRecordComment(recordId uint64, comment *Comment) (id string,
 
 // DeleteComment - Delete single comment
 func (app *App) DeleteComment(recordId uint64, commentId uint64) error {
+	return app.DeleteCommentContext(context.Background(), recordId, commentId)
+}
+
+// DeleteCommentContext is like DeleteComment but uses ctx for the API request.
+func (app *App) DeleteCommentContext(ctx context.Context, recordId uint64, commentId uint64) error {
 	type requestBody struct {
 		App       uint64 `json:"app,string"`
 		RecordID  uint64 `json:"record,string"`
 		CommentID uint64 `json:"comment,string"`
 	}
 	requestData := requestBody{app.AppId, recordId, commentId}
-	data, _ := json.Marshal(requestData)
-
-	req, err := app.newRequest("DELETE", "record/comment", bytes.NewReader(data))
-	if err != nil {
-		return err
-	}
-	resp, err := app.do(req)
-	if err != nil {
-		return err
-	}
-	_, err = parseResponse(resp)
+	_, err := app.call(ctx, "DELETE", "record/comment", requestData)
 	return err
 }
 
@@ -1122,27 +1405,19 @@ func decodeFieldInfo(t AppFormFields, ret map[string]*FieldInfo) {
 //
 // If successful, a mapping between field codes and FieldInfo is returned.
 func (app *App) Fields() (map[string]*FieldInfo, error) {
+	return app.FieldsContext(context.Background())
+}
+
+// FieldsContext is like Fields but uses ctx for the API request.
+func (app *App) FieldsContext(ctx context.Context) (map[string]*FieldInfo, error) {
 	type request_body struct {
 		App uint64 `json:"app,string"`
 	}
-	data, _ := json.Marshal(request_body{app.AppId})
-	req, err := app.newRequest("GET", "app/form/fields", bytes.NewReader(data))
-	if err != nil {
-		return nil, err
-	}
-	resp, err := app.do(req)
+	body, err := app.call(ctx, "GET", "app/form/fields", request_body{app.AppId})
 	if err != nil {
 		return nil, err
 	}
-	body, err := parseResponse(resp)
 	fieldCodes := getFieldCodeFromFormField(body)
-	if err != nil {
-		fmt.Println("error:", err)
-	}
-
-	if err != nil {
-		return nil, err
-	}
 
 	var t AppFormFields
 	err = json.Unmarshal(body, &t)
@@ -1220,6 +1495,11 @@ func updateIndexToField(fieldCode [][]byte, fieldInfo map[string]*FieldInfo) {
 
 // CreateCursor return the meta data of the Cursor in this application
 func (app *App) CreateCursor(fields []string, query string, size uint64) (*Cursor, error) {
+	return app.CreateCursorContext(context.Background(), fields, query, size)
+}
+
+// CreateCursorContext is like CreateCursor but uses ctx for the API request.
+func (app *App) CreateCursorContext(ctx context.Context, fields []string, query string, size uint64) (*Cursor, error) {
 	type cursor struct {
 		App    uint64   `json:"app"`
 		Fields []string `json:"fields"`
@@ -1227,17 +1507,7 @@ func (app *App) CreateCursor(fields []string, query string, size uint64) (*Curso
 		Query  string   `json:"query"`
 	}
 	data := cursor{App: app.AppId, Fields: fields, Size: size, Query: query}
-	jsonData, _ := json.Marshal(data)
-	url := app.createUrl("records/cursor", "")
-	request, err := app.NewRequest("POST", url.String(), bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, err
-	}
-	response, err := app.do(request)
-	if err != nil {
-		return nil, err
-	}
-	body, err := parseResponse(response)
+	body, err := app.call(ctx, "POST", "records/cursor", data)
 	if err != nil {
 		return nil, err
 	}
@@ -1250,26 +1520,15 @@ func (app *App) CreateCursor(fields []string, query string, size uint64) (*Curso
 
 // DeleteCursor - Delete cursor by id
 func (app *App) DeleteCursor(id string) error {
+	return app.DeleteCursorContext(context.Background(), id)
+}
+
+// DeleteCursorContext is like DeleteCursor but uses ctx for the API request.
+func (app *App) DeleteCursorContext(ctx context.Context, id string) error {
 	type requestBody struct {
 		Id string `json:"id"`
 	}
-	data, err := json.Marshal(requestBody{Id: id})
-	if err != nil {
-		return err
-	}
-
-	url := app.createUrl("records/cursor", "")
-	request, err := app.NewRequest("DELETE", url.String(), bytes.NewBuffer(data))
-	if err != nil {
-		return err
-	}
-
-	response, err := app.do(request)
-	if err != nil {
-		return err
-	}
-
-	_, err = parseResponse(response)
+	_, err := app.call(ctx, "DELETE", "records/cursor", requestBody{Id: id})
 	if err != nil {
 		return err
 	}
@@ -1280,22 +1539,19 @@ func (app *App) DeleteCursor(id string) error {
 // Using Cursor Id to get all records
 // GetRecordsByCursor return the meta data of the Record in this application
 func (app *App) GetRecordsByCursor(id string) (*GetRecordsCursorResponse, error) {
-	url := app.createUrl("records/cursor", "id="+id)
-	request, err := app.NewRequest("GET", url.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-	response, err := app.do(request)
-	if err != nil {
-		return nil, err
-	}
-	data, err := parseResponse(response)
-	if err != nil {
-		return nil, err
-	}
-	recordsCursorResponse, err := DecodeGetRecordsCursorResponse(data)
-	if err != nil {
+	return app.GetRecordsByCursorContext(context.Background(), id)
+}
+
+// GetRecordsByCursorContext is like GetRecordsByCursor but uses ctx for the API request.
+func (app *App) GetRecordsByCursorContext(ctx context.Context, id string) (*GetRecordsCursorResponse, error) {
+	recs, rr, err := app.readRecords(ctx, &APICall{
+		API:    "records/cursor",
+		Method: "GET",
+		AppId:  app.AppId,
+		Query:  "id=" + url.QueryEscape(id),
+	})
+	if recs == nil {
 		return nil, err
 	}
-	return recordsCursorResponse, nil
+	return &GetRecordsCursorResponse{Records: recs, Next: rr.HasNext()}, err
 }
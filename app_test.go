@@ -5,17 +5,18 @@
 package kintone
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -37,17 +38,12 @@ const (
 	BASIC_AUTH_PASSWORD    = "basic"
 )
 
-func createServerTest(mux *http.ServeMux) (*httptest.Server, error) {
-	ts := httptest.NewUnstartedServer(mux)
-	listen, err := net.Listen("tcp", KINTONE_DOMAIN)
-	if err != nil {
-		return nil, err
-	}
+// KINTONE_BASE_URL is the URL of the test server started by TestMain.
+var KINTONE_BASE_URL string
 
-	ts.Listener.Close()
-	ts.Listener = listen
-	ts.StartTLS()
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+func createServerTest(mux *http.ServeMux) (*httptest.Server, error) {
+	ts := httptest.NewServer(mux)
+	KINTONE_BASE_URL = ts.URL
 	return ts, nil
 }
 
@@ -62,6 +58,13 @@ func createServerMux() *http.ServeMux {
 	mux.HandleFunc("/k/v1/app/status.json", handleResponseProcess)
 	mux.HandleFunc("/k/v1/form.json", handleResponseForm)
 	mux.HandleFunc("/k/guest/1/v1/form.json", handleResponseForm)
+	mux.HandleFunc("/k/v1/app/form/fields.json", handleResponseFormFields)
+	mux.HandleFunc("/k/guest/1/v1/app/form/fields.json", handleResponseFormFields)
+	mux.HandleFunc("/k/v1/app.json", handleResponseApp)
+	mux.HandleFunc("/k/guest/1/v1/app.json", handleResponseApp)
+	mux.HandleFunc("/k/v1/apps.json", handleResponseApps)
+	mux.HandleFunc("/k/v1/space.json", handleResponseSpace)
+	mux.HandleFunc("/v1/users.json", handleResponseUsers)
 	return mux
 }
 
@@ -114,6 +117,13 @@ func handleResponseForm(response http.ResponseWriter, request *http.Request) {
 	}
 }
 
+func handleResponseFormFields(response http.ResponseWriter, request *http.Request) {
+	checkAuth(response, request)
+	checkContentType(response, request)
+	testData := GetDataTestFormFields()
+	fmt.Fprint(response, testData.output)
+}
+
 func handleResponseRecordsCursor(response http.ResponseWriter, request *http.Request) {
 	checkAuth(response, request)
 	if request.Method == "GET" {
@@ -202,6 +212,37 @@ func handleResponseGetRecords(response http.ResponseWriter, request *http.Reques
 	}
 }
 
+func handleResponseApp(response http.ResponseWriter, request *http.Request) {
+	checkAuth(response, request)
+	checkContentType(response, request)
+	testData := GetTestDataApp()
+	fmt.Fprint(response, testData.output)
+}
+
+func handleResponseApps(response http.ResponseWriter, request *http.Request) {
+	checkAuth(response, request)
+	checkContentType(response, request)
+	testData := GetTestDataApps()
+	fmt.Fprint(response, testData.output)
+}
+
+func handleResponseSpace(response http.ResponseWriter, request *http.Request) {
+	checkAuth(response, request)
+	checkContentType(response, request)
+	testData := GetTestDataSpace()
+	fmt.Fprint(response, testData.output)
+}
+
+func handleResponseUsers(response http.ResponseWriter, request *http.Request) {
+	checkAuth(response, request)
+	if request.URL.Query().Get("codes[0]") == "" {
+		http.Error(response, "Bad request", http.StatusBadRequest)
+		return
+	}
+	testData := GetTestDataUsers()
+	fmt.Fprint(response, testData.output)
+}
+
 func handleResponseGetRecordsComments(response http.ResponseWriter, request *http.Request) {
 	checkAuth(response, request)
 	checkContentType(response, request)
@@ -223,7 +264,7 @@ func TestMain(m *testing.M) {
 
 func newApp() *App {
 	return &App{
-		Domain:   KINTONE_DOMAIN,
+		BaseURL:  KINTONE_BASE_URL,
 		User:     KINTONE_USERNAME,
 		Password: KINTONE_PASSWORD,
 		AppId:    KINTONE_APP_ID,
@@ -232,7 +273,7 @@ func newApp() *App {
 
 func newAppWithGuest() *App {
 	return &App{
-		Domain:       KINTONE_DOMAIN,
+		BaseURL:      KINTONE_BASE_URL,
 		AppId:        KINTONE_APP_ID,
 		User:         KINTONE_USERNAME,
 		Password:     KINTONE_PASSWORD,
@@ -243,11 +284,58 @@ func newAppWithGuest() *App {
 func newAppWithToken() *App {
 	return &App{
 		AppId:    KINTONE_APP_ID,
-		Domain:   KINTONE_DOMAIN,
+		BaseURL:  KINTONE_BASE_URL,
 		ApiToken: KINTONE_API_TOKEN,
 	}
 }
 
+// newTestApp returns an app like newApp whose requests are served by
+// handler instead of the shared mux.  The server is closed with t.
+func newTestApp(t *testing.T, handler http.Handler) *App {
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	app := newApp()
+	app.BaseURL = ts.URL
+	return app
+}
+
+// respond returns a handler which responds with status and body.
+func respond(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}
+}
+
+// requestLog records requests to a test server, which may be served
+// concurrently.
+type requestLog struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+// add records s.
+func (l *requestLog) add(s string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, s)
+}
+
+// addBody reads and records the body of r.
+func (l *requestLog) addBody(r *http.Request) []byte {
+	b, _ := ioutil.ReadAll(r.Body)
+	l.add(string(b))
+	return b
+}
+
+// all returns the recorded entries.
+func (l *requestLog) all() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.entries...)
+}
+
 func TestAddRecord(t *testing.T) {
 	testData := GetDataTestAddRecord()
 	app := newApp()
@@ -516,3 +604,143 @@ func TestLookupFieldInFieldInfo(t *testing.T) {
 		fmt.Printf("\nApp have no Lookup field\n");
 	}
 }
+
+func TestContextCanceled(t *testing.T) {
+	app := newApp()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := app.GetRecordContext(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetRecordContext must fail with context.Canceled: %v", err)
+	}
+	if _, err := app.GetRecordsContext(ctx, nil, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetRecordsContext must fail with context.Canceled: %v", err)
+	}
+	if _, err := app.GetRecordCommentsContext(ctx, 1, "asc", 0, 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetRecordCommentsContext must fail with context.Canceled: %v", err)
+	}
+}
+
+// closeRecorder is a transport which signals closed when a response
+// body is closed.
+type closeRecorder struct {
+	closed chan struct{}
+}
+
+func (c *closeRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &closeNotifier{ReadCloser: resp.Body, closed: c.closed}
+	return resp, nil
+}
+
+type closeNotifier struct {
+	io.ReadCloser
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (n *closeNotifier) Close() error {
+	n.once.Do(func() { close(n.closed) })
+	return n.ReadCloser.Close()
+}
+
+func TestDownloadCanceled(t *testing.T) {
+	app := newTestApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "first chunk")
+		w.(http.Flusher).Flush()
+		// The rest of the body never comes.
+		<-r.Context().Done()
+	}))
+	rec := &closeRecorder{make(chan struct{})}
+	app.Client = &http.Client{Transport: rec}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fd, err := app.DownloadContext(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, len("first chunk"))
+	if _, err := io.ReadFull(fd.Reader, buf); err != nil || string(buf) != "first chunk" {
+		t.Fatalf("unexpected contents: %q %v", buf, err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := io.ReadAll(fd.Reader); !errors.Is(err, context.Canceled) {
+		t.Errorf("reading must fail with context.Canceled: %v", err)
+	}
+	select {
+	case <-rec.closed:
+	case <-time.After(5 * time.Second):
+		t.Error("the response body must be closed")
+	}
+}
+
+func TestNewApp(t *testing.T) {
+	app, err := NewApp(*newApp())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Client == nil || app.Client.Jar == nil {
+		t.Error("NewApp must set a client with a cookie jar")
+	}
+	if app.Timeout != DEFAULT_TIMEOUT {
+		t.Errorf("unexpected Timeout: %v", app.Timeout)
+	}
+
+	if _, err := NewApp(App{User: KINTONE_USERNAME, Password: KINTONE_PASSWORD}); err == nil {
+		t.Error("NewApp must fail without Domain")
+	}
+	if _, err := NewApp(App{Domain: KINTONE_DOMAIN, User: KINTONE_USERNAME}); err == nil {
+		t.Error("NewApp must fail without Password")
+	}
+	if _, err := NewApp(App{Domain: KINTONE_DOMAIN}); err == nil {
+		t.Error("NewApp must fail without credentials")
+	}
+}
+
+func TestConcurrentApp(t *testing.T) {
+	apps := []*App{newApp()}
+	if app, err := NewApp(*newApp()); err != nil {
+		t.Fatal(err)
+	} else {
+		apps = append(apps, app)
+	}
+	for _, app := range apps {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := app.GetRecord(1); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	app := &App{Domain: KINTONE_DOMAIN, GuestSpaceId: 3}
+	if u, err := app.apiURL("records", ""); err != nil || u.String() != "https://"+KINTONE_DOMAIN+"/k/guest/3/v1/records.json" {
+		t.Errorf("unexpected URL: %v %v", u, err)
+	}
+
+	app = &App{Domain: KINTONE_DOMAIN, BaseURL: "http://proxy.example.com:8080/kintone/"}
+	if u, err := app.apiURL("records/cursor", "id=1"); err != nil || u.String() != "http://proxy.example.com:8080/kintone/k/v1/records/cursor.json?id=1" {
+		t.Errorf("unexpected URL: %v %v", u, err)
+	}
+
+	app.BaseURL = "ftp://example.com"
+	if _, err := app.GetRecord(1); err == nil {
+		t.Error("GetRecord must fail with an invalid BaseURL")
+	}
+	if _, err := NewApp(App{BaseURL: "example.com", ApiToken: KINTONE_API_TOKEN}); err == nil {
+		t.Error("NewApp must fail with an invalid BaseURL")
+	}
+}
@@ -469,3 +469,231 @@ func GetTestDataUpdateRecordByKey() *TestData {
 		}`,
 	}
 }
+
+func GetTestDataApp() *TestData {
+	return &TestData{
+		input: []interface{}{1},
+		output: `
+		{
+			"appId":"1",
+			"code":"TASK",
+			"name":"Tasks",
+			"description":"",
+			"spaceId":"2",
+			"threadId":"3",
+			"createdAt":"2019-03-11T04:50:00.000Z",
+			"creator":{
+				"code":"Administrator",
+				"name":"Administrator"
+			},
+			"modifiedAt":"2019-03-11T04:50:00.000Z",
+			"modifier":{
+				"code":"Administrator",
+				"name":"Administrator"
+			}
+		}`,
+	}
+}
+
+func GetTestDataApps() *TestData {
+	return &TestData{
+		input: []interface{}{"Task", []uint64{2}, 0, 10},
+		output: `
+		{
+			"apps":[
+				{
+					"appId":"1",
+					"code":"TASK",
+					"name":"Tasks",
+					"description":"",
+					"spaceId":"2",
+					"threadId":"3",
+					"createdAt":"2019-03-11T04:50:00.000Z",
+					"creator":{
+						"code":"Administrator",
+						"name":"Administrator"
+					},
+					"modifiedAt":"2019-03-11T04:50:00.000Z",
+					"modifier":{
+						"code":"Administrator",
+						"name":"Administrator"
+					}
+				}
+			]
+		}`,
+	}
+}
+
+func GetTestDataSpace() *TestData {
+	return &TestData{
+		input: []interface{}{2},
+		output: `
+		{
+			"id":"2",
+			"name":"Projects",
+			"defaultThread":"3",
+			"isPrivate":true,
+			"creator":{
+				"code":"Administrator",
+				"name":"Administrator"
+			},
+			"modifier":{
+				"code":"Administrator",
+				"name":"Administrator"
+			},
+			"memberCount":"10",
+			"body":"<b>Projects</b>",
+			"attachedApps":[
+				{
+					"threadId":"3",
+					"appId":"1",
+					"code":"TASK",
+					"name":"Tasks"
+				}
+			],
+			"isGuest":false
+		}`,
+	}
+}
+
+func GetTestDataUsers() *TestData {
+	return &TestData{
+		input: []interface{}{[]string{"user1"}, 0, 10},
+		output: `
+		{
+			"users":[
+				{
+					"id":"1",
+					"code":"user1",
+					"name":"User 1",
+					"email":"user1@example.com",
+					"valid":true,
+					"description":"",
+					"ctime":"2019-03-11T04:50:00Z",
+					"mtime":"2019-03-11T04:50:00Z"
+				}
+			]
+		}`,
+	}
+}
+
+func GetDataTestFormFields() *TestData {
+	return &TestData{
+		output: `
+		{
+			"properties":{
+				"string_1":{
+					"type":"SINGLE_LINE_TEXT",
+					"code":"string_1",
+					"label":"string_1",
+					"noLabel":false,
+					"required":true,
+					"unique":true,
+					"maxLength":"64",
+					"minLength":"",
+					"defaultValue":"",
+					"expression":"",
+					"hideExpression":false
+				},
+				"number_1":{
+					"type":"NUMBER",
+					"code":"number_1",
+					"label":"number_1",
+					"noLabel":true,
+					"required":false,
+					"unique":false,
+					"maxValue":"",
+					"minValue":"",
+					"defaultValue":"12345",
+					"digit":true,
+					"displayScale":"4",
+					"unit":"",
+					"unitPosition":"BEFORE"
+				},
+				"checkbox_1":{
+					"type":"CHECK_BOX",
+					"code":"checkbox_1",
+					"label":"checkbox_1",
+					"noLabel":false,
+					"required":false,
+					"options":{
+						"sample1":{"label":"sample1","index":"0"},
+						"sample2":{"label":"sample2","index":"1"}
+					},
+					"defaultValue":["sample1"],
+					"align":"HORIZONTAL"
+				},
+				"lookup_1":{
+					"type":"SINGLE_LINE_TEXT",
+					"code":"lookup_1",
+					"label":"lookup_1",
+					"noLabel":false,
+					"required":false,
+					"lookup":{
+						"relatedApp":{"app":"2","code":""},
+						"relatedKeyField":"key",
+						"fieldMappings":[],
+						"lookupPickerFields":[],
+						"filterCond":"",
+						"sort":"$id desc"
+					}
+				}
+			},
+			"revision":"2"
+		}`,
+	}
+}
+
+func GetTestDataRevisionConflict() *TestData {
+	return &TestData{
+		output: `{"code": "GAIA_CO02", "id": "x", "message": "The revision is not the latest."}`,
+	}
+}
+
+func GetTestDataInvalidValue() *TestData {
+	return &TestData{
+		output: `{"code": "CB_VA01", "id": "x", "message": "Invalid value."}`,
+	}
+}
+
+func GetTestDataRecordNotFound() *TestData {
+	return &TestData{
+		output: `{"code": "GAIA_RE01", "id": "x", "message": "Not found."}`,
+	}
+}
+
+func GetTestDataUpsertRecord() *TestData {
+	return &TestData{
+		output: `{"id": "8", "revision": "1", "operation": "INSERT"}`,
+	}
+}
+
+func GetTestDataUpsertRecords() *TestData {
+	return &TestData{
+		output: `
+		{
+			"records": [
+				{"id": "3", "revision": "5", "operation": "UPDATE"},
+				{"id": "9", "revision": "1", "operation": "INSERT"}
+			]
+		}`,
+	}
+}
+
+func GetTestDataUpdateRecord() *TestData {
+	return &TestData{
+		output: `{"revision": "6"}`,
+	}
+}
+
+func GetTestDataUpdateRecords() *TestData {
+	return &TestData{
+		output: `
+		{
+			"records": [
+				{"id": "3", "revision": "6"},
+				{"id": "4", "revision": "2"}
+			]
+		}`,
+	}
+}
@@ -0,0 +1,331 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// bulkChunkSize is the maximum number of records written at once.
+const bulkChunkSize = 100
+
+// BulkOptions configures the Bulk* methods.
+type BulkOptions struct {
+	Concurrency int  // Number of requests sent at once.  Default 1.
+	Atomic      bool // Send up to MAX_TRANSACTION_OPS chunks in a bulkRequest so that they are written all or nothing.
+}
+
+// BulkReport reports which records a Bulk* method wrote.
+type BulkReport struct {
+	Succeeded []int         // Indices of the records written, in ascending order.
+	Failed    []BulkFailure // Records not written, in ascending order of the index.
+	Ids       []uint64      // IDs of the records added by BulkAddRecords; 0 for failed ones.
+}
+
+// BulkFailure reports a record which was not written.
+type BulkFailure struct {
+	Index int   // Index of the record.
+	Err   error // Error of the record, or of the request which should have written it.
+}
+
+// BulkError is returned by the Bulk* methods if some records were not
+// written.  Report is the same as the one returned with the error.
+type BulkError struct {
+	Report *BulkReport
+}
+
+func (e *BulkError) Error() string {
+	f := e.Report.Failed
+	return fmt.Sprintf("kintone: %d records were not written: %v", len(f), f[0].Err)
+}
+
+// Unwrap returns the distinct errors of the failed requests.
+func (e *BulkError) Unwrap() []error {
+	var errs []error
+	for i, f := range e.Report.Failed {
+		if i == 0 || f.Err != e.Report.Failed[i-1].Err {
+			errs = append(errs, f.Err)
+		}
+	}
+	return errs
+}
+
+// BulkAddRecords is like AddRecords but takes any number of records.
+//
+// Records are split into chunks of 100 records, each of which is added
+// by a request.  With opts.Atomic, chunks are grouped into bulkRequest
+// batches instead, each of which is applied all or nothing.  Records of
+// a failed request are not written while the other requests go on;
+// they are reported in the returned BulkReport, together with *BulkError.
+// Records named by the field errors of a failed request fail with
+// *AppError of their own field errors, whose record indices are those
+// of recs; the other records of the request fail with an error wrapping
+// that of the request.  opts may be nil for the defaults.
+func (app *App) BulkAddRecords(recs []*Record, opts *BulkOptions) (*BulkReport, error) {
+	return app.BulkAddRecordsContext(context.Background(), recs, opts)
+}
+
+// BulkAddRecordsContext is like BulkAddRecords but uses ctx for the API requests.
+func (app *App) BulkAddRecordsContext(ctx context.Context, recs []*Record, opts *BulkOptions) (*BulkReport, error) {
+	ids := make([]uint64, len(recs))
+	report, err := app.bulkWrite(ctx, len(recs), opts, ids, nil,
+		func(ctx context.Context, lo, hi int) error {
+			sids, err := app.AddRecordsContext(ctx, recs[lo:hi])
+			if err != nil {
+				return err
+			}
+			for i, s := range sids {
+				if ids[lo+i], err = strconv.ParseUint(s, 10, 64); err != nil {
+					return ErrInvalidResponse
+				}
+			}
+			return nil
+		},
+		func(tx *Transaction, lo, hi int) {
+			tx.AddRecords(app.AppId, recs[lo:hi])
+		})
+	report.Ids = ids
+	return report, err
+}
+
+// BulkUpdateRecords is like UpdateRecords but takes any number of
+// records.  See BulkAddRecords for opts and the result.
+func (app *App) BulkUpdateRecords(recs []*Record, ignoreRevision bool, opts *BulkOptions) (*BulkReport, error) {
+	return app.BulkUpdateRecordsContext(context.Background(), recs, ignoreRevision, opts)
+}
+
+// BulkUpdateRecordsContext is like BulkUpdateRecords but uses ctx for the API requests.
+func (app *App) BulkUpdateRecordsContext(ctx context.Context, recs []*Record, ignoreRevision bool, opts *BulkOptions) (*BulkReport, error) {
+	return app.bulkWrite(ctx, len(recs), opts, nil, nil,
+		func(ctx context.Context, lo, hi int) error {
+			return app.UpdateRecordsContext(ctx, recs[lo:hi], ignoreRevision)
+		},
+		func(tx *Transaction, lo, hi int) {
+			tx.UpdateRecords(app.AppId, recs[lo:hi], ignoreRevision)
+		})
+}
+
+// BulkUpdateRecordsByKey is like UpdateRecordsByKey but takes any number
+// of records.  See BulkAddRecords for opts and the result.  Records
+// without keyField fail with ErrInvalidKeyField, and the other records
+// of their requests are not sent.
+func (app *App) BulkUpdateRecordsByKey(recs []*Record, ignoreRevision bool, keyField string, opts *BulkOptions) (*BulkReport, error) {
+	return app.BulkUpdateRecordsByKeyContext(context.Background(), recs, ignoreRevision, keyField, opts)
+}
+
+// BulkUpdateRecordsByKeyContext is like BulkUpdateRecordsByKey but uses ctx for the API requests.
+func (app *App) BulkUpdateRecordsByKeyContext(ctx context.Context, recs []*Record, ignoreRevision bool, keyField string, opts *BulkOptions) (*BulkReport, error) {
+	return app.bulkWrite(ctx, len(recs), opts, nil,
+		func(i int) error {
+			if _, _, err := app.keyedFields(recs[i], keyField); err != nil {
+				return fmt.Errorf("records[%d]: %w", i, err)
+			}
+			return nil
+		},
+		func(ctx context.Context, lo, hi int) error {
+			return app.UpdateRecordsByKeyContext(ctx, recs[lo:hi], ignoreRevision, keyField)
+		},
+		func(tx *Transaction, lo, hi int) {
+			tx.UpdateRecordsByKey(app.AppId, recs[lo:hi], ignoreRevision, keyField)
+		})
+}
+
+// BulkDeleteRecords is like DeleteRecords but takes any number of IDs.
+// Indices in the report are those of ids.  See BulkAddRecords for opts
+// and the result.
+func (app *App) BulkDeleteRecords(ids []uint64, opts *BulkOptions) (*BulkReport, error) {
+	return app.BulkDeleteRecordsContext(context.Background(), ids, opts)
+}
+
+// BulkDeleteRecordsContext is like BulkDeleteRecords but uses ctx for the API requests.
+func (app *App) BulkDeleteRecordsContext(ctx context.Context, ids []uint64, opts *BulkOptions) (*BulkReport, error) {
+	return app.bulkWrite(ctx, len(ids), opts, nil, nil,
+		func(ctx context.Context, lo, hi int) error {
+			return app.DeleteRecordsContext(ctx, ids[lo:hi])
+		},
+		func(tx *Transaction, lo, hi int) {
+			tx.DeleteRecords(app.AppId, ids[lo:hi])
+		})
+}
+
+// bulkWrite writes n records by chunks.  write sends a chunk of records
+// [lo, hi) by itself, and queue adds it to a transaction.  If ids is not
+// nil, the IDs of the records written in transactions are stored in it.
+// If check is not nil, it validates the i-th record before sending.
+func (app *App) bulkWrite(ctx context.Context, n int, opts *BulkOptions, ids []uint64, check func(i int) error,
+	write func(ctx context.Context, lo, hi int) error, queue func(tx *Transaction, lo, hi int)) (*BulkReport, error) {
+	var o BulkOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 1
+	}
+
+	// A job is a chunk, or chunks of a transaction.
+	per := 1
+	if o.Atomic {
+		per = MAX_TRANSACTION_OPS
+	}
+	var jobs [][][2]int
+	for lo := 0; lo < n; lo += bulkChunkSize * per {
+		var job [][2]int
+		for c := lo; c < n && c < lo+bulkChunkSize*per; c += bulkChunkSize {
+			job = append(job, [2]int{c, min(c+bulkChunkSize, n)})
+		}
+		jobs = append(jobs, job)
+	}
+
+	// Each record is written by a job only, so errs needs no lock.
+	errs := make([]error, n)
+	fail := func(lo, hi int, err error) {
+		for i := lo; i < hi; i++ {
+			errs[i] = err
+		}
+	}
+	// failChunk fails the chunk [lo, hi) which err made fail.  Records
+	// named by the field errors of err fail with their own errors.
+	failChunk := func(lo, hi int, err error) {
+		var ae *AppError
+		if !errors.As(err, &ae) {
+			fail(lo, hi, err)
+			return
+		}
+		named := splitFieldErrors(ae, lo, hi)
+		if len(named) == 0 {
+			fail(lo, hi, err)
+			return
+		}
+		var te *TransactionError
+		errors.As(err, &te)
+		notWritten := fmt.Errorf("kintone: not written with invalid records: %w", err)
+		for i := lo; i < hi; i++ {
+			switch e := named[i]; {
+			case e == nil:
+				errs[i] = notWritten
+			case te != nil:
+				errs[i] = &TransactionError{te.Index, e}
+			default:
+				errs[i] = e
+			}
+		}
+	}
+	run := func(job [][2]int) {
+		lo, hi := job[0][0], job[len(job)-1][1]
+		if err := ctx.Err(); err != nil {
+			fail(lo, hi, err)
+			return
+		}
+		if check != nil {
+			var invalid error
+			for i := lo; i < hi; i++ {
+				if errs[i] = check(i); errs[i] != nil && invalid == nil {
+					invalid = errs[i]
+				}
+			}
+			if invalid != nil {
+				notSent := fmt.Errorf("kintone: not sent with invalid records: %w", invalid)
+				for i := lo; i < hi; i++ {
+					if errs[i] == nil {
+						errs[i] = notSent
+					}
+				}
+				return
+			}
+		}
+		if !o.Atomic {
+			if err := write(ctx, lo, hi); err != nil {
+				failChunk(lo, hi, err)
+			}
+			return
+		}
+		tx := app.NewTransaction()
+		for _, c := range job {
+			queue(tx, c[0], c[1])
+		}
+		results, err := tx.CommitContext(ctx)
+		if err != nil {
+			// Chunks other than the failed one were rolled back.
+			rolledBack := err
+			var te *TransactionError
+			if errors.As(err, &te) {
+				rolledBack = fmt.Errorf("kintone: rolled back: %w", err)
+			}
+			for k, c := range job {
+				if te != nil && te.Index != k {
+					fail(c[0], c[1], rolledBack)
+				} else {
+					failChunk(c[0], c[1], err)
+				}
+			}
+			return
+		}
+		if ids != nil {
+			for k, c := range job {
+				copy(ids[c[0]:c[1]], results[k].Ids)
+			}
+		}
+	}
+
+	ch := make(chan [][2]int)
+	var wg sync.WaitGroup
+	for w := 0; w < o.Concurrency && w < len(jobs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range ch {
+				run(job)
+			}
+		}()
+	}
+	for _, job := range jobs {
+		ch <- job
+	}
+	close(ch)
+	wg.Wait()
+
+	report := &BulkReport{}
+	for i, err := range errs {
+		if err == nil {
+			report.Succeeded = append(report.Succeeded, i)
+		} else {
+			report.Failed = append(report.Failed, BulkFailure{i, err})
+		}
+	}
+	if len(report.Failed) > 0 {
+		return report, &BulkError{report}
+	}
+	return report, nil
+}
+
+// splitFieldErrors splits the field errors of ae, the error of writing
+// the records [lo, hi), by record.  The errors are keyed by the indices
+// of the records in the bulk write, and so are their field errors.
+func splitFieldErrors(ae *AppError, lo, hi int) map[int]*AppError {
+	named := make(map[int]*AppError)
+	for _, fe := range ae.FieldErrorList() {
+		if fe.RecordIndex < 0 || fe.RecordIndex >= hi-lo {
+			continue
+		}
+		i := lo + fe.RecordIndex
+		e := named[i]
+		if e == nil {
+			c := *ae
+			c.FieldErrors = make(map[string]*FieldError)
+			e = &c
+			named[i] = e
+		}
+		g := *fe
+		g.RecordIndex = i
+		g.Path = fmt.Sprintf("records[%d]", i) + strings.TrimPrefix(fe.Path, fmt.Sprintf("records[%d]", fe.RecordIndex))
+		e.FieldErrors[g.Path] = &g
+	}
+	return named
+}
@@ -0,0 +1,217 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newBulkTestApp returns an app which fails to write the record titled
+// "bad" or whose ID is 2050.  The title "invalid" is reported as a field
+// error.  Added records get their titles as IDs.
+func newBulkTestApp(t *testing.T) (*App, *int) {
+	var mu sync.Mutex
+	requests := 0
+	type payload struct {
+		Records []struct {
+			Title struct {
+				Value string `json:"value"`
+			} `json:"title"`
+		} `json:"records"`
+		Ids []uint64 `json:"ids"`
+	}
+	// check returns the response of a records request.
+	check := func(method string, p payload) (int, string) {
+		if len(p.Records) > 100 || len(p.Ids) > 100 {
+			t.Errorf("too many records: %d %d", len(p.Records), len(p.Ids))
+		}
+		var ids []string
+		for i, r := range p.Records {
+			if r.Title.Value == "bad" {
+				return http.StatusBadRequest, GetTestDataInvalidValue().output
+			}
+			if r.Title.Value == "invalid" {
+				return http.StatusBadRequest, fmt.Sprintf(`{"code":"CB_VA01","id":"x","message":"Invalid value.",`+
+					`"errors":{"records[%d].title.value":{"messages":["invalid title"]}}}`, i)
+			}
+			ids = append(ids, `"`+r.Title.Value+`"`)
+		}
+		for _, id := range p.Ids {
+			if id == 2050 {
+				return http.StatusNotFound, GetTestDataRecordNotFound().output
+			}
+		}
+		if method == "POST" {
+			return http.StatusOK, `{"ids":[` + strings.Join(ids, ",") + `]}`
+		}
+		return http.StatusOK, `{}`
+	}
+	app := newTestApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/k/v1/records.json" {
+			var p payload
+			json.Unmarshal(b, &p)
+			status, body := check(r.Method, p)
+			respond(status, body)(w, r)
+			return
+		}
+
+		var bulk struct {
+			Requests []struct {
+				Method  string  `json:"method"`
+				Payload payload `json:"payload"`
+			} `json:"requests"`
+		}
+		json.Unmarshal(b, &bulk)
+		results := make([]string, len(bulk.Requests))
+		failed := false
+		for i, req := range bulk.Requests {
+			status, body := check(req.Method, req.Payload)
+			results[i] = body
+			if status != http.StatusOK {
+				failed = true
+				w.WriteHeader(status)
+			}
+		}
+		if failed {
+			for i := range results {
+				if !strings.Contains(results[i], "code") {
+					results[i] = "{}"
+				}
+			}
+		}
+		fmt.Fprintf(w, `{"results":[%s]}`, strings.Join(results, ","))
+	}))
+	return app, &requests
+}
+
+func TestBulkAddRecords(t *testing.T) {
+	app, requests := newBulkTestApp(t)
+	recs := make([]*Record, 250)
+	for i := range recs {
+		title := fmt.Sprint(i + 1)
+		if i == 120 {
+			title = "bad"
+		}
+		recs[i] = NewRecord(map[string]interface{}{"title": SingleLineTextField(title)})
+	}
+	report, err := app.BulkAddRecords(recs, &BulkOptions{Concurrency: 3})
+	var be *BulkError
+	if !errors.As(err, &be) || be.Report != report {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ae *AppError
+	if !errors.As(err, &ae) || ae.Code != "CB_VA01" {
+		t.Errorf("the error of the request must be wrapped: %v", err)
+	}
+	if len(report.Succeeded) != 150 || len(report.Failed) != 100 || report.Failed[0].Index != 100 || report.Failed[99].Index != 199 {
+		t.Errorf("unexpected report: %d %d", len(report.Succeeded), len(report.Failed))
+	}
+	if report.Ids[0] != 1 || report.Ids[100] != 0 || report.Ids[249] != 250 {
+		t.Errorf("unexpected IDs: %v", report.Ids)
+	}
+	if *requests != 3 {
+		t.Errorf("unexpected requests: %d", *requests)
+	}
+
+	report, err = app.BulkAddRecords(recs[:100], nil)
+	if err != nil || len(report.Succeeded) != 100 || report.Ids[99] != 100 {
+		t.Errorf("unexpected result: %v", err)
+	}
+}
+
+func TestBulkDeleteRecordsAtomic(t *testing.T) {
+	app, requests := newBulkTestApp(t)
+	ids := make([]uint64, 2500)
+	for i := range ids {
+		ids[i] = uint64(i + 1)
+	}
+	report, err := app.BulkDeleteRecords(ids, &BulkOptions{Concurrency: 2, Atomic: true})
+	if err == nil || len(report.Succeeded) != 2000 || len(report.Failed) != 500 {
+		t.Fatalf("unexpected result: %d %v", len(report.Succeeded), err)
+	}
+	if *requests != 2 {
+		t.Errorf("unexpected requests: %d", *requests)
+	}
+
+	var te *TransactionError
+	if f := report.Failed[0]; f.Index != 2000 || !errors.As(f.Err, &te) || !errors.Is(f.Err, ErrRecordNotFound) {
+		t.Errorf("unexpected failure: %+v", f)
+	}
+	if f := report.Failed[100]; f.Index != 2100 || !strings.Contains(f.Err.Error(), "rolled back") || !errors.As(f.Err, &te) {
+		t.Errorf("unexpected failure: %+v", f)
+	}
+	if errs := err.(*BulkError).Unwrap(); len(errs) != 2 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestBulkFieldErrors(t *testing.T) {
+	app, _ := newBulkTestApp(t)
+	recs := make([]*Record, 250)
+	for i := range recs {
+		title := fmt.Sprint(i + 1)
+		if i == 150 {
+			title = "invalid"
+		}
+		recs[i] = NewRecord(map[string]interface{}{"title": SingleLineTextField(title)})
+	}
+	for _, atomic := range []bool{false, true} {
+		// The transaction rolls back the other chunks too.
+		lo, hi := 100, 200
+		if atomic {
+			lo, hi = 0, 250
+		}
+		report, err := app.BulkAddRecords(recs, &BulkOptions{Atomic: atomic})
+		if err == nil || len(report.Failed) != hi-lo {
+			t.Fatalf("unexpected result: %v", err)
+		}
+		var ae *AppError
+		f := report.Failed[150-lo]
+		if f.Index != 150 || !errors.As(f.Err, &ae) {
+			t.Fatalf("unexpected failure: %+v", f)
+		}
+		fe := ae.FieldErrors["records[150].title.value"]
+		if len(ae.FieldErrors) != 1 || fe == nil || fe.RecordIndex != 150 || fe.FieldCode != "title" {
+			t.Errorf("unexpected field errors: %v", ae.FieldErrorList())
+		}
+		if f := report.Failed[100-lo]; f.Index != 100 || !strings.Contains(f.Err.Error(), "not written") || !errors.As(f.Err, &ae) {
+			t.Errorf("unexpected failure: %+v", f)
+		}
+	}
+}
+
+func TestBulkUpdateRecordsByKeyWithoutKey(t *testing.T) {
+	app, requests := newBulkTestApp(t)
+	recs := make([]*Record, 250)
+	for i := range recs {
+		recs[i] = NewRecord(map[string]interface{}{"key": SingleLineTextField(fmt.Sprint(i))})
+	}
+	delete(recs[150].Fields, "key")
+	report, err := app.BulkUpdateRecordsByKey(recs, true, "key", nil)
+	if !errors.Is(err, ErrInvalidKeyField) || len(report.Succeeded) != 150 || len(report.Failed) != 100 {
+		t.Fatalf("unexpected result: %v", err)
+	}
+	if f := report.Failed[50]; f.Index != 150 || !errors.Is(f.Err, ErrInvalidKeyField) || !strings.HasPrefix(f.Err.Error(), "records[150]:") {
+		t.Errorf("unexpected failure: %+v", f)
+	}
+	if f := report.Failed[0]; f.Index != 100 || !strings.Contains(f.Err.Error(), "not sent") {
+		t.Errorf("unexpected failure: %+v", f)
+	}
+	if *requests != 2 {
+		t.Errorf("unexpected requests: %d", *requests)
+	}
+}
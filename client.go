@@ -0,0 +1,313 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Client provides kintone API client for a domain.
+//
+// A Client owns the domain, the credentials, the HTTP client, the rate
+// limiting and the guest space routing shared by every application in
+// the domain.
+// App returns a lightweight handle to call the application APIs
+// through the Client.  Domain-wide APIs such as GetApps and GetSpace
+// are methods of Client itself.
+//
+// Like App, a Client is safe for concurrent use as long as its fields
+// are not modified while API methods are running.
+//
+//	client, err := kintone.NewClient(kintone.Client{
+//		Domain:   "example.cybozu.com",
+//		User:     "user1",
+//		Password: "password",
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	records, err := client.App(25).GetRecords(nil, "limit 3")
+type Client struct {
+	Domain            string        // domain name.  ex: "sample.cybozu.com"
+	BaseURL           string        // Base URL to use instead of "https://" + Domain.
+	User              string        // User account for API.
+	Password          string        // User password for API.
+	ApiToken          string        // API token(s) used for every app.  See also AppWithToken.
+	GuestSpaceId      uint64        // guest space ID.
+	HTTPClient        *http.Client  // Specialized client.
+	Timeout           time.Duration // Timeout for API responses.
+	Retry             *RetryPolicy  // Retry policy for transient failures.  nil disables retries.
+	Limiter           *Limiter      // Limiter shared by every app.  nil disables limiting.
+	Middlewares       []Middleware  // Middlewares applied to every API call.  The first is the outermost.
+	Logger            *slog.Logger  // Logger for API calls.  nil disables logging.
+	LenientDecoding   bool          // Return partially decoded records with *DecodeError.
+	SendAllFields     bool          // Send every field in updates instead of changed writable ones.
+	SyncRecords       bool          // Store IDs and revisions returned by writes in the passed records.
+	basicAuth         bool          // true to use Basic Authentication.
+	basicAuthUser     string        // User name for Basic Authentication.
+	basicAuthPassword string        // Password for Basic Authentication.
+	extUserAgent      string        // User-agent request header string
+	keyFields         *keyCache     // Valid key fields of upserts, shared by the apps.
+}
+
+// NewClient validates config and returns a new Client configured with it.
+//
+// Unset HTTPClient and Timeout are filled with a new *http.Client that
+// has its own cookie jar and DEFAULT_TIMEOUT respectively.
+func NewClient(config Client) (*Client, error) {
+	err := validateConfig(config.Domain, config.BaseURL, config.User, config.Password, config.ApiToken, config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c := config
+	if c.HTTPClient == nil {
+		if c.HTTPClient, err = newHTTPClient(); err != nil {
+			return nil, err
+		}
+	}
+	if c.Timeout == time.Duration(0) {
+		c.Timeout = DEFAULT_TIMEOUT
+	}
+	c.keyFields = newKeyCache()
+	return &c, nil
+}
+
+// SetBasicAuth enables use of HTTP basic authentication for access
+// to kintone.
+func (c *Client) SetBasicAuth(user, password string) {
+	c.basicAuth = true
+	c.basicAuthUser = user
+	c.basicAuthPassword = password
+}
+
+// SetUserAgentHeader set custom user-agent header for http request
+func (c *Client) SetUserAgentHeader(userAgentHeader string) {
+	c.extUserAgent = userAgentHeader
+}
+
+// App returns a handle of the application whose ID is id.
+//
+// The returned App shares the HTTP client and the settings of c.
+func (c *Client) App(id uint64) *App {
+	return c.AppWithToken(id, c.ApiToken)
+}
+
+// AppWithToken is like App but authenticates with the API token of
+// the application instead of c.ApiToken.
+func (c *Client) AppWithToken(id uint64, token string) *App {
+	return &App{
+		Domain:            c.Domain,
+		BaseURL:           c.BaseURL,
+		User:              c.User,
+		Password:          c.Password,
+		AppId:             id,
+		Client:            c.HTTPClient,
+		Timeout:           c.Timeout,
+		ApiToken:          token,
+		GuestSpaceId:      c.GuestSpaceId,
+		Retry:             c.Retry,
+		Limiter:           c.Limiter,
+		Middlewares:       c.Middlewares,
+		Logger:            c.Logger,
+		LenientDecoding:   c.LenientDecoding,
+		SendAllFields:     c.SendAllFields,
+		SyncRecords:       c.SyncRecords,
+		basicAuth:         c.basicAuth,
+		basicAuthUser:     c.basicAuthUser,
+		basicAuthPassword: c.basicAuthPassword,
+		extUserAgent:      c.extUserAgent,
+		keyFields:         c.keyFields,
+	}
+}
+
+// GuestSpace returns a copy of c that routes requests to the guest
+// space whose ID is id.
+func (c *Client) GuestSpace(id uint64) *Client {
+	gc := *c
+	gc.GuestSpaceId = id
+	return &gc
+}
+
+// call sends a domain-wide API request and returns the response body.
+func (c *Client) call(ctx context.Context, method, api string, body interface{}) ([]byte, error) {
+	return c.App(0).call(ctx, method, api, body)
+}
+
+// AppInfo is the information of an application.
+type AppInfo struct {
+	AppId       uint64 `json:"appId,string"`
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	SpaceId     string `json:"spaceId"`  // empty if the app is not in a space
+	ThreadId    string `json:"threadId"` // empty if the app is not in a space
+	CreatedAt   string `json:"createdAt"`
+	Creator     User   `json:"creator"`
+	ModifiedAt  string `json:"modifiedAt"`
+	Modifier    User   `json:"modifier"`
+}
+
+// GetApp fetches the information of an application.
+func (c *Client) GetApp(id uint64) (*AppInfo, error) {
+	return c.GetAppContext(context.Background(), id)
+}
+
+// GetAppContext is like GetApp but uses ctx for the API request.
+func (c *Client) GetAppContext(ctx context.Context, id uint64) (*AppInfo, error) {
+	type request_body struct {
+		Id uint64 `json:"id,string"`
+	}
+	body, err := c.call(ctx, "GET", "app", request_body{id})
+	if err != nil {
+		return nil, err
+	}
+	var info AppInfo
+	if json.Unmarshal(body, &info) != nil {
+		return nil, ErrInvalidResponse
+	}
+	return &info, nil
+}
+
+// GetApps fetches the information of applications.
+//
+// If name is not empty, applications whose names contain it are returned.
+// If spaceIds is not empty, only applications in the spaces are returned.
+// Up to 100 applications can be retrieved at once.
+func (c *Client) GetApps(name string, spaceIds []uint64, offset, limit uint64) ([]*AppInfo, error) {
+	return c.GetAppsContext(context.Background(), name, spaceIds, offset, limit)
+}
+
+// GetAppsContext is like GetApps but uses ctx for the API request.
+func (c *Client) GetAppsContext(ctx context.Context, name string, spaceIds []uint64, offset, limit uint64) ([]*AppInfo, error) {
+	if limit > 100 {
+		return nil, ErrTooMany
+	}
+	type request_body struct {
+		Name     string   `json:"name,omitempty"`
+		SpaceIds []uint64 `json:"spaceIds,omitempty"`
+		Offset   uint64   `json:"offset"`
+		Limit    uint64   `json:"limit,omitempty"`
+	}
+	body, err := c.call(ctx, "GET", "apps", request_body{name, spaceIds, offset, limit})
+	if err != nil {
+		return nil, err
+	}
+	var t struct {
+		Apps []*AppInfo `json:"apps"`
+	}
+	if json.Unmarshal(body, &t) != nil {
+		return nil, ErrInvalidResponse
+	}
+	return t.Apps, nil
+}
+
+// Space is the information of a space.
+type Space struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	DefaultThread string `json:"defaultThread"`
+	IsPrivate     bool   `json:"isPrivate"`
+	IsGuest       bool   `json:"isGuest"`
+	Creator       User   `json:"creator"`
+	Modifier      User   `json:"modifier"`
+	MemberCount   string `json:"memberCount"`
+	Body          string `json:"body"` // HTML of the space portal
+	AttachedApps  []struct {
+		AppId    string `json:"appId"`
+		Code     string `json:"code"`
+		Name     string `json:"name"`
+		ThreadId string `json:"threadId"`
+	} `json:"attachedApps"`
+}
+
+// GetSpace fetches the information of a space.
+func (c *Client) GetSpace(id uint64) (*Space, error) {
+	return c.GetSpaceContext(context.Background(), id)
+}
+
+// GetSpaceContext is like GetSpace but uses ctx for the API request.
+func (c *Client) GetSpaceContext(ctx context.Context, id uint64) (*Space, error) {
+	type request_body struct {
+		Id uint64 `json:"id,string"`
+	}
+	body, err := c.call(ctx, "GET", "space", request_body{id})
+	if err != nil {
+		return nil, err
+	}
+	var space Space
+	if json.Unmarshal(body, &space) != nil {
+		return nil, ErrInvalidResponse
+	}
+	return &space, nil
+}
+
+// UserInfo is the information of a cybozu.com user.
+type UserInfo struct {
+	Id          string `json:"id"`
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	Valid       bool   `json:"valid"`
+	Description string `json:"description"`
+	CreatedAt   string `json:"ctime"`
+	UpdatedAt   string `json:"mtime"`
+}
+
+// GetUsers fetches users of the domain through cybozu.com user API.
+//
+// If codes is empty, all users are returned in the order of their IDs.
+// Up to 100 users can be retrieved at once.  This API only supports
+// password authentication by an administrator.
+func (c *Client) GetUsers(codes []string, offset, size uint64) ([]*UserInfo, error) {
+	return c.GetUsersContext(context.Background(), codes, offset, size)
+}
+
+// GetUsersContext is like GetUsers but uses ctx for the API request.
+func (c *Client) GetUsersContext(ctx context.Context, codes []string, offset, size uint64) ([]*UserInfo, error) {
+	if size > 100 || len(codes) > 100 {
+		return nil, ErrTooMany
+	}
+	q := url.Values{}
+	for i, code := range codes {
+		q.Set(fmt.Sprintf("codes[%d]", i), code)
+	}
+	if offset > 0 {
+		q.Set("offset", fmt.Sprint(offset))
+	}
+	if size > 0 {
+		q.Set("size", fmt.Sprint(size))
+	}
+	app := c.App(0)
+	u, err := app.endpoint("/v1/users.json", q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	req, err := app.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := app.do(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := parseResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	var t struct {
+		Users []*UserInfo `json:"users"`
+	}
+	if json.Unmarshal(body, &t) != nil {
+		return nil, ErrInvalidResponse
+	}
+	return t.Users, nil
+}
@@ -0,0 +1,109 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"testing"
+)
+
+func newClient(t *testing.T) *Client {
+	c, err := NewClient(Client{
+		BaseURL:  KINTONE_BASE_URL,
+		User:     KINTONE_USERNAME,
+		Password: KINTONE_PASSWORD,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestNewClient(t *testing.T) {
+	c := newClient(t)
+	if c.HTTPClient == nil || c.Timeout != DEFAULT_TIMEOUT {
+		t.Error("NewClient must fill in the defaults")
+	}
+	if _, err := NewClient(Client{BaseURL: KINTONE_BASE_URL}); err == nil {
+		t.Error("NewClient must fail without credentials")
+	}
+}
+
+func TestClientApp(t *testing.T) {
+	c := newClient(t)
+	c.SetBasicAuth(BASIC_AUTH_USER, BASIC_AUTH_PASSWORD)
+	app := c.App(KINTONE_APP_ID)
+	if app.AppId != KINTONE_APP_ID || app.Client != c.HTTPClient || !app.HasBasicAuth() {
+		t.Error("App must share the settings of the client")
+	}
+	if _, err := app.GetRecord(1); err != nil {
+		t.Error(err)
+	}
+
+	if app := c.AppWithToken(2, KINTONE_API_TOKEN); app.ApiToken != KINTONE_API_TOKEN {
+		t.Error("AppWithToken must use the given token")
+	}
+	if app := c.GuestSpace(KINTONE_GUEST_SPACE_ID).App(1); app.GuestSpaceId != KINTONE_GUEST_SPACE_ID {
+		t.Error("GuestSpace must route apps to the guest space")
+	}
+	if c.GuestSpaceId != 0 {
+		t.Error("GuestSpace must not modify the client")
+	}
+}
+
+func TestClientGetApp(t *testing.T) {
+	testData := GetTestDataApp()
+	c := newClient(t)
+	info, err := c.GetApp(uint64(testData.input[0].(int)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.AppId != 1 || info.Name != "Tasks" || info.Creator.Code != "Administrator" {
+		t.Errorf("unexpected app: %+v", info)
+	}
+	if _, err := c.GuestSpace(KINTONE_GUEST_SPACE_ID).GetApp(1); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClientGetApps(t *testing.T) {
+	testData := GetTestDataApps()
+	c := newClient(t)
+	apps, err := c.GetApps(testData.input[0].(string), testData.input[1].([]uint64),
+		uint64(testData.input[2].(int)), uint64(testData.input[3].(int)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps) != 1 || apps[0].SpaceId != "2" {
+		t.Errorf("unexpected apps: %+v", apps)
+	}
+	if _, err := c.GetApps("", nil, 0, 101); err != ErrTooMany {
+		t.Error("GetApps must fail with ErrTooMany")
+	}
+}
+
+func TestClientGetSpace(t *testing.T) {
+	testData := GetTestDataSpace()
+	c := newClient(t)
+	space, err := c.GetSpace(uint64(testData.input[0].(int)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if space.Name != "Projects" || !space.IsPrivate || len(space.AttachedApps) != 1 {
+		t.Errorf("unexpected space: %+v", space)
+	}
+}
+
+func TestClientGetUsers(t *testing.T) {
+	testData := GetTestDataUsers()
+	c := newClient(t)
+	users, err := c.GetUsers(testData.input[0].([]string),
+		uint64(testData.input[1].(int)), uint64(testData.input[2].(int)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Code != "user1" || !users[0].Valid {
+		t.Errorf("unexpected users: %+v", users)
+	}
+}
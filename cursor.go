@@ -1,7 +1,9 @@
 package kintone
 
 import (
+	"context"
 	"encoding/json"
+	"log/slog"
 )
 
 // Object Cursor structure
@@ -25,6 +27,11 @@ func decodeCursor(b []byte) (c *Cursor, err error) {
 }
 
 func DecodeGetRecordsCursorResponse(b []byte) (rc *GetRecordsCursorResponse, err error) {
+	return (&Decoder{Logger: slog.Default()}).DecodeGetRecordsCursorResponse(b)
+}
+
+// DecodeGetRecordsCursorResponse decodes JSON response for cursor get API.
+func (d *Decoder) DecodeGetRecordsCursorResponse(b []byte) (rc *GetRecordsCursorResponse, err error) {
 	var t struct {
 		Next bool `json:"next"`
 	}
@@ -32,10 +39,154 @@ func DecodeGetRecordsCursorResponse(b []byte) (rc *GetRecordsCursorResponse, err
 	if err != nil {
 		return nil, err
 	}
-	listRecord, err := DecodeRecords(b)
-	if err != nil {
+	listRecord, err := d.DecodeRecords(b)
+	if listRecord == nil && err != nil {
 		return nil, err
 	}
 	getRecordsCursorResponse := &GetRecordsCursorResponse{Records: listRecord, Next: t.Next}
-	return getRecordsCursorResponse, nil
+	return getRecordsCursorResponse, err
+}
+
+// RecordIterator reads records through a cursor page by page.
+//
+// ex:
+//
+//	it, err := app.IterateRecords(nil, "order by $id asc", 500)
+//	if err != nil {
+//		return err
+//	}
+//	defer it.Close()
+//	for it.Next() {
+//		process(it.Record())
+//	}
+//	return it.Err()
+//
+// kintone limits the number of open cursors; always Close the iterator,
+// typically with defer so that the cursor is deleted even on panic.
+type RecordIterator struct {
+	app    *App
+	ctx    context.Context
+	id     string
+	recs   []*Record
+	rec    *Record
+	more   bool // true if kintone has more records.
+	n      int  // Number of records fetched so far.
+	err    error
+	derr   *DecodeError // Errors of the pages partially decoded.
+	closed bool
+	cerr   error // The result of Close.
+}
+
+// IterateRecords creates a cursor and returns a RecordIterator over it.
+//
+// fields, query and size are passed to CreateCursor.
+func (app *App) IterateRecords(fields []string, query string, size uint64) (*RecordIterator, error) {
+	return app.IterateRecordsContext(context.Background(), fields, query, size)
+}
+
+// IterateRecordsContext is like IterateRecords but uses ctx for the API
+// requests of the iterator.
+func (app *App) IterateRecordsContext(ctx context.Context, fields []string, query string, size uint64) (*RecordIterator, error) {
+	c, err := app.CreateCursorContext(ctx, fields, query, size)
+	if err != nil {
+		return nil, err
+	}
+	return &RecordIterator{app: app, ctx: ctx, id: c.Id, more: true}, nil
+}
+
+// Next advances the iterator to the next record.  It returns false at
+// the end of the records or on an error, and closes the iterator then.
+func (it *RecordIterator) Next() bool {
+	for len(it.recs) == 0 {
+		if it.closed || !it.more {
+			it.rec = nil
+			it.Close()
+			return false
+		}
+		rc, err := it.app.GetRecordsByCursorContext(it.ctx, it.id)
+		if e, ok := err.(*DecodeError); ok && rc != nil {
+			if it.derr == nil {
+				it.derr = &DecodeError{}
+			}
+			for _, fe := range e.Errors {
+				fe.RecordIndex += it.n
+			}
+			it.derr.Errors = append(it.derr.Errors, e.Errors...)
+		} else if err != nil {
+			it.err = err
+			it.rec = nil
+			it.Close()
+			return false
+		}
+		it.recs, it.more = rc.Records, rc.Next
+		it.n += len(rc.Records)
+	}
+	it.rec, it.recs = it.recs[0], it.recs[1:]
+	return true
+}
+
+// Record returns the current record.
+func (it *RecordIterator) Record() *Record {
+	return it.rec
+}
+
+// Err returns the error which stopped the iteration, if any.  With
+// LenientDecoding, fields which could not be decoded do not stop the
+// iteration; they are reported by *DecodeError at the end instead.
+func (it *RecordIterator) Err() error {
+	if it.err == nil && it.derr != nil {
+		return it.derr
+	}
+	return it.err
+}
+
+// Close deletes the cursor unless kintone has already deleted it after
+// all records were read.  The cursor is deleted even if the context of
+// the iterator is canceled.  Close may be called more than once.
+func (it *RecordIterator) Close() error {
+	if it.closed {
+		return it.cerr
+	}
+	it.closed = true
+	it.recs = nil
+	if it.more {
+		it.cerr = it.app.DeleteCursorContext(context.WithoutCancel(it.ctx), it.id)
+	}
+	return it.cerr
+}
+
+// StreamRecordsContext is like IterateRecordsContext but sends the
+// records to the returned channel, which is closed at the end of the
+// records.  The error channel then yields the error which stopped the
+// stream, if any, and is closed.
+//
+// The cursor is deleted at the end of the records, or when ctx is
+// canceled.  To stop reading before the end, cancel ctx; otherwise the
+// goroutine sending the records and the cursor are never released.
+func (app *App) StreamRecordsContext(ctx context.Context, fields []string, query string, size uint64) (<-chan *Record, <-chan error) {
+	recc := make(chan *Record)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(recc)
+		it, err := app.IterateRecordsContext(ctx, fields, query, size)
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer it.Close()
+		for it.Next() {
+			select {
+			case recc <- it.Record():
+			case <-ctx.Done():
+				it.Close()
+				errc <- ctx.Err()
+				return
+			}
+		}
+		if it.Err() != nil {
+			errc <- it.Err()
+		}
+	}()
+	return recc, errc
 }
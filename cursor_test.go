@@ -1,6 +1,13 @@
 package kintone
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -11,3 +18,150 @@ func TestDecodeCursor(t *testing.T) {
 		t.Errorf("TestDecodeCursor is failed: %v", err)
 	}
 }
+
+// newCursorTestApp returns an app which serves n records by a cursor
+// in pages of size records.  The title of the record broken, if not 0,
+// cannot be decoded.
+func newCursorTestApp(t *testing.T, n, size, broken int) (*App, *int32) {
+	var deleted int32
+	var mu sync.Mutex
+	next := 0
+	app := newTestApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case "POST":
+			next = 0
+			fmt.Fprintf(w, `{"id":"c1","totalCount":"%d"}`, n)
+		case "GET":
+			if r.URL.Query().Get("id") != "c1" || next < 0 {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"code":"GAIA_CU01","id":"x","message":"Cursor not found."}`)
+				return
+			}
+			var recs []string
+			for ; next < n && len(recs) < size; next++ {
+				title := `"a"`
+				if next+1 == broken {
+					title = `{}`
+				}
+				recs = append(recs, fmt.Sprintf(`{"$id":{"type":"__ID__","value":"%d"},"title":{"type":"SINGLE_LINE_TEXT","value":%s}}`, next+1, title))
+			}
+			fmt.Fprintf(w, `{"records":[%s],"next":%v}`, strings.Join(recs, ","), next < n)
+			if next >= n {
+				next = -1
+			}
+		case "DELETE":
+			atomic.AddInt32(&deleted, 1)
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	return app, &deleted
+}
+
+func TestRecordIterator(t *testing.T) {
+	app, deleted := newCursorTestApp(t, 25, 10, 0)
+	it, err := app.IterateRecords(nil, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var id uint64
+	for it.Next() {
+		id++
+		if it.Record().Id() != id {
+			t.Fatalf("unexpected record: %d", it.Record().Id())
+		}
+	}
+	if it.Err() != nil || id != 25 {
+		t.Errorf("unexpected end: %d %v", id, it.Err())
+	}
+	if err := it.Close(); err != nil || *deleted != 0 {
+		t.Errorf("finished cursors must not be deleted: %v %d", err, *deleted)
+	}
+
+	it, err = app.IterateRecords(nil, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() { recover() }()
+		defer it.Close()
+		for it.Next() {
+			panic("boom")
+		}
+	}()
+	if *deleted != 1 || it.Next() {
+		t.Errorf("cursor must be deleted on panic: %d", *deleted)
+	}
+	it.Close()
+	if *deleted != 1 {
+		t.Errorf("cursor must be deleted once: %d", *deleted)
+	}
+}
+
+func TestRecordIteratorLenient(t *testing.T) {
+	app, deleted := newCursorTestApp(t, 25, 10, 13)
+	app.LenientDecoding = true
+	it, err := app.IterateRecords(nil, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for it.Next() {
+		n++
+	}
+	var de *DecodeError
+	if !errors.As(it.Err(), &de) || len(de.Errors) != 1 || de.Errors[0].RecordIndex != 12 {
+		t.Fatalf("unexpected error: %v", it.Err())
+	}
+	if n != 25 {
+		t.Errorf("partially decoded pages must be kept: %d", n)
+	}
+	if err := it.Close(); err != nil || *deleted != 0 {
+		t.Errorf("finished cursors must not be deleted: %v %d", err, *deleted)
+	}
+}
+
+func TestStreamRecords(t *testing.T) {
+	app, deleted := newCursorTestApp(t, 25, 10, 0)
+	recc, errc := app.StreamRecordsContext(context.Background(), nil, "", 10)
+	n := 0
+	for range recc {
+		n++
+	}
+	if err := <-errc; err != nil || n != 25 {
+		t.Errorf("unexpected end: %d %v", n, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	recc, errc = app.StreamRecordsContext(ctx, nil, "", 10)
+	<-recc
+	cancel()
+	for range recc {
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if atomic.LoadInt32(deleted) != 1 {
+		t.Errorf("cursor must be deleted on cancellation: %d", *deleted)
+	}
+}
+
+func TestStreamRecordsAbandoned(t *testing.T) {
+	app, deleted := newCursorTestApp(t, 25, 10, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	recc, errc := app.StreamRecordsContext(ctx, nil, "", 10)
+	<-recc
+	// Stop reading the records without draining the channel.
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if atomic.LoadInt32(deleted) != 1 {
+		t.Errorf("abandoned cursor must be deleted: %d", *deleted)
+	}
+	if _, ok := <-recc; ok {
+		t.Error("records must not be sent after cancellation")
+	}
+}
@@ -16,6 +16,13 @@ See https://kintone.dev/en/ for API specs.
 		AppId:    25,
 	}
 
+NewApp validates the same configuration once and fills in the defaults:
+	app, err := kintone.NewApp(kintone.App{
+		Domain:   "example.cybozu.com",
+		ApiToken: "token",
+		AppId:    25,
+	})
+
 To retrieve 3 records from a kintone app (id=25):
 	records, err := app.GetRecords(nil, "limit 3")
 	if err != nil {
@@ -23,6 +30,9 @@ To retrieve 3 records from a kintone app (id=25):
 	}
 	// use records
 
+Every API method has a Context variant which aborts the request when ctx is done:
+	records, err := app.GetRecordsContext(ctx, nil, "limit 3")
+
 To retrieve 10 latest comments in record (id=3) from a kintone app (id=25)
 	var offset uint64 = 0
 	var limit uint64 = 10
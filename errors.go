@@ -0,0 +1,133 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Categories of *AppError.  Test them with errors.Is:
+//
+//	if errors.Is(err, kintone.ErrRevisionConflict) {
+//		// reload the record and try again
+//	}
+var (
+	ErrPermissionDenied = errors.New("Permission denied")
+	ErrRevisionConflict = errors.New("Revision conflict")
+	ErrRecordNotFound   = errors.New("Record not found")
+	ErrInvalidQuery     = errors.New("Invalid query")
+	ErrThrottled        = errors.New("Throttled")
+)
+
+// Is reports whether e falls into the category target.
+func (e *AppError) Is(target error) bool {
+	switch target {
+	case ErrPermissionDenied:
+		return e.HttpStatusCode == http.StatusForbidden ||
+			e.Code == "CB_NO02" || e.Code == "GAIA_NO01"
+	case ErrRevisionConflict:
+		return e.Code == "GAIA_CO02"
+	case ErrRecordNotFound:
+		return e.Code == "GAIA_RE01"
+	case ErrInvalidQuery:
+		return strings.HasPrefix(e.Code, "GAIA_IQ")
+	case ErrThrottled:
+		if e.HttpStatusCode == http.StatusTooManyRequests {
+			return true
+		}
+		for _, c := range DefaultRetryCodes {
+			if e.Code == c {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FieldError is a validation error reported for a field.
+//
+// Path is the key of the errors property such as
+// "records[3].Price.value" or "record.Table.value[0].value.Qty.value".
+type FieldError struct {
+	Path         string
+	RecordIndex  int    // Index of the record in bulk requests, or -1.
+	FieldCode    string // Field code, or Path if it does not point a field.
+	Row          int    // Row index in the subtable, or -1.
+	SubFieldCode string // Field code in the subtable row.
+	Messages     []string
+}
+
+func (e *FieldError) Error() string {
+	return e.Path + ": " + strings.Join(e.Messages, ", ")
+}
+
+var fieldErrorPath = regexp.MustCompile(
+	`^(?:records\[(\d+)\]\.|record\.)?([^.\[\]]+)(?:\.value(?:\[(\d+)\]\.value\.([^.\[\]]+)(?:\.value)?)?)?$`)
+
+// parseFieldError parses the path of a field error.
+func parseFieldError(path string, messages []string) *FieldError {
+	fe := &FieldError{
+		Path:        path,
+		RecordIndex: -1,
+		FieldCode:   path,
+		Row:         -1,
+		Messages:    messages,
+	}
+	m := fieldErrorPath.FindStringSubmatch(path)
+	if m == nil {
+		return fe
+	}
+	if len(m[1]) > 0 {
+		fe.RecordIndex, _ = strconv.Atoi(m[1])
+	}
+	fe.FieldCode = m[2]
+	if len(m[3]) > 0 {
+		fe.Row, _ = strconv.Atoi(m[3])
+		fe.SubFieldCode = m[4]
+	}
+	return fe
+}
+
+// parseFieldErrors parses the errors property of an error response.
+func parseFieldErrors(v interface{}) map[string]*FieldError {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	errs := make(map[string]*FieldError, len(m))
+	for path, e := range m {
+		var messages []string
+		if o, ok := e.(map[string]interface{}); ok {
+			if a, ok := o["messages"].([]interface{}); ok {
+				for _, s := range a {
+					if s, ok := s.(string); ok {
+						messages = append(messages, s)
+					}
+				}
+			}
+		}
+		errs[path] = parseFieldError(path, messages)
+	}
+	return errs
+}
+
+// FieldErrorList returns e.FieldErrors sorted by the path.
+func (e *AppError) FieldErrorList() []*FieldError {
+	paths := make([]string, 0, len(e.FieldErrors))
+	for path := range e.FieldErrors {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	list := make([]*FieldError, len(paths))
+	for i, path := range paths {
+		list[i] = e.FieldErrors[path]
+	}
+	return list
+}
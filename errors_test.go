@@ -0,0 +1,75 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestFieldErrors(t *testing.T) {
+	app := newTestApp(t, respond(http.StatusBadRequest, `{"code":"CB_VA01","id":"x","message":"Missing or invalid input.","errors":{
+		"records[3].Price.value":{"messages":["Required.","Must be a number."]},
+		"record.Table.value[1].value.Qty.value":{"messages":["Too large."]},
+		"query":{"messages":["Invalid."]}}}`))
+	_, err := app.AddRecords([]*Record{NewRecord(nil)})
+	var ae *AppError
+	if !errors.As(err, &ae) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ae.Errors) == 0 {
+		t.Error("Errors must be kept")
+	}
+	expected := []*FieldError{
+		{Path: "query", RecordIndex: -1, FieldCode: "query", Row: -1, Messages: []string{"Invalid."}},
+		{Path: "record.Table.value[1].value.Qty.value", RecordIndex: -1, FieldCode: "Table", Row: 1,
+			SubFieldCode: "Qty", Messages: []string{"Too large."}},
+		{Path: "records[3].Price.value", RecordIndex: 3, FieldCode: "Price", Row: -1,
+			Messages: []string{"Required.", "Must be a number."}},
+	}
+	if list := ae.FieldErrorList(); !reflect.DeepEqual(list, expected) {
+		for _, fe := range list {
+			t.Errorf("unexpected field error: %+v", fe)
+		}
+	}
+}
+
+func TestErrorCategories(t *testing.T) {
+	tests := []struct {
+		status int
+		code   string
+		target error
+	}{
+		{http.StatusForbidden, "CB_NO02", ErrPermissionDenied},
+		{http.StatusConflict, "GAIA_CO02", ErrRevisionConflict},
+		{http.StatusNotFound, "GAIA_RE01", ErrRecordNotFound},
+		{http.StatusBadRequest, "GAIA_IQ11", ErrInvalidQuery},
+		{http.StatusTooManyRequests, "", ErrThrottled},
+		{http.StatusServiceUnavailable, "GAIA_TM12", ErrThrottled},
+	}
+	all := []error{ErrPermissionDenied, ErrRevisionConflict, ErrRecordNotFound, ErrInvalidQuery, ErrThrottled}
+	for _, tt := range tests {
+		err := fmt.Errorf("wrapped: %w", &AppError{HttpStatusCode: tt.status, Code: tt.code})
+		for _, target := range all {
+			if errors.Is(err, target) != (target == tt.target) {
+				t.Errorf("%d %s: errors.Is(%v) = %v", tt.status, tt.code, target, !(target == tt.target))
+			}
+		}
+	}
+}
+
+func TestParseResponseNonObject(t *testing.T) {
+	for _, body := range []string{`[1,2]`, `"oops"`, `null`, `{"errors":[1]}`} {
+		app := newTestApp(t, respond(http.StatusBadRequest, body))
+		_, err := app.GetRecord(1)
+		var ae *AppError
+		if !errors.As(err, &ae) || ae.HttpStatusCode != http.StatusBadRequest {
+			t.Errorf("%s: unexpected error: %v", body, err)
+		}
+	}
+}
@@ -491,18 +491,18 @@ type SubTableField []*Record
 
 func (f SubTableField) JSONValue() interface{} {
 	type sub_record struct {
-		Record *Record `json:"value"`
+		Record recordFields `json:"value"`
 	}
 	type sub_record_with_id struct {
-		Id     uint64  `json:"id,string"`
-		Record *Record `json:"value"`
+		Id     uint64       `json:"id,string"`
+		Record recordFields `json:"value"`
 	}
 	recs := make([]interface{}, 0, len(f))
 	for _, rec := range f {
 		if rec.id == 0 {
-			recs = append(recs, sub_record{rec})
+			recs = append(recs, sub_record{rec.Fields})
 		} else {
-			recs = append(recs, sub_record_with_id{rec.id, rec})
+			recs = append(recs, sub_record_with_id{rec.id, rec.Fields})
 		}
 	}
 	return recs
@@ -515,6 +515,29 @@ func (f SubTableField) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// RawField is a field of a type which this package does not know.
+//
+// The original type and JSON value are kept as is so that such fields
+// survive decoding and encoding records.
+type RawField struct {
+	Type  string          // Field type such as "REFERENCE_TABLE".
+	Value json.RawMessage // JSON value of the field.
+}
+
+func (f RawField) JSONValue() interface{} {
+	if f.Value == nil {
+		return nil
+	}
+	return f.Value
+}
+
+func (f RawField) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":  f.Type,
+		"value": f.JSONValue(),
+	})
+}
+
 // IsBuiltinField returns true if the field is a built-in field.
 func IsBuiltinField(o interface{}) bool {
 	switch o.(type) {
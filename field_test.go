@@ -741,3 +741,27 @@ func TestIsBuiltinField(t *testing.T) {
 		t.Error("CreatorField is built-in")
 	}
 }
+
+func TestRawField(t *testing.T) {
+	t.Parallel()
+
+	j := `{"record":{"ref":{"type":"REFERENCE_TABLE","value":{"app":"3","rows":[1,2.50,null]}},` +
+		`"table":{"type":"SUBTABLE","value":[{"id":"7","value":{"x":{"type":"NEW_TYPE","value":null}}}]}}}`
+	rec, err := DecodeRecord([]byte(j))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, ok := rec.Fields["ref"].(RawField)
+	if !ok || ref.Type != "REFERENCE_TABLE" || IsBuiltinField(ref) {
+		t.Fatalf("unknown field must be RawField: %#v", rec.Fields["ref"])
+	}
+	b, err := json.Marshal(rec.Fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"ref":{"type":"REFERENCE_TABLE","value":{"app":"3","rows":[1,2.50,null]}},` +
+		`"table":{"type":"SUBTABLE","value":[{"id":"7","value":{"x":{"type":"NEW_TYPE","value":null}}}]}}`
+	if string(b) != expected {
+		t.Errorf("RawField must round-trip: %s", b)
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/kintone-labs/go-kintone
 
-go 1.17
+go 1.21
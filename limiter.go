@@ -0,0 +1,177 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned when a request would exceed
+// Limiter.DailyQuota of the application.
+var ErrQuotaExceeded = errors.New("Daily request quota exceeded")
+
+// Limiter limits requests sent to a kintone domain.
+//
+// kintone limits the number of concurrent requests per domain and the
+// number of requests per application per day.  Share one Limiter
+// between every App of a domain, for instance by setting it to
+// Client.Limiter, to keep them within the limits.
+//
+// A Limiter is safe for concurrent use.
+type Limiter struct {
+	sem      chan struct{} // nil if unlimited
+	interval time.Duration // minimum interval between requests; 0 if unlimited
+
+	// DailyQuota, if positive, makes requests to an application fail
+	// with ErrQuotaExceeded once this many requests were sent to it
+	// on the same day.
+	DailyQuota int
+
+	// Location decides when a day begins for the daily counters.
+	// nil means time.Local.
+	Location *time.Location
+
+	mu     sync.Mutex
+	next   time.Time        // earliest time of the next request
+	day    string           // the day of counts
+	counts map[uint64]int   // requests per app on day
+	now    func() time.Time // for testing
+}
+
+// NewLimiter returns a Limiter which allows up to maxInFlight concurrent
+// requests and ratePerSecond requests per second.  Zero or negative
+// values mean no limit.
+func NewLimiter(maxInFlight int, ratePerSecond float64) *Limiter {
+	l := &Limiter{counts: make(map[uint64]int)}
+	if maxInFlight > 0 {
+		l.sem = make(chan struct{}, maxInFlight)
+	}
+	if ratePerSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / ratePerSecond)
+	}
+	return l
+}
+
+func (l *Limiter) clock() time.Time {
+	if l.now != nil {
+		return l.now()
+	}
+	return time.Now()
+}
+
+// today returns the current day in l.Location.  l.mu must be held.
+func (l *Limiter) today() string {
+	loc := l.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	d := l.clock().In(loc).Format("2006-01-02")
+	if d != l.day {
+		l.day = d
+		l.counts = make(map[uint64]int)
+	}
+	return d
+}
+
+// Count returns the number of requests sent to the application today.
+func (l *Limiter) Count(appId uint64) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.today()
+	return l.counts[appId]
+}
+
+// Remaining returns the number of requests that can be sent to the
+// application today, or -1 if DailyQuota is not set.
+func (l *Limiter) Remaining(appId uint64) int {
+	if l.DailyQuota <= 0 {
+		return -1
+	}
+	if n := l.DailyQuota - l.Count(appId); n > 0 {
+		return n
+	}
+	return 0
+}
+
+// acquire waits until a request to the application is allowed.
+// The returned function must be called when the request is finished.
+//
+// appId 0 means a request not bound to an application.  A request
+// counts against DailyQuota as soon as it is allowed to wait; the count
+// is given back if ctx is done before the request is allowed.
+func (l *Limiter) acquire(ctx context.Context, appId uint64) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	day := l.today()
+	if appId != 0 {
+		if l.DailyQuota > 0 && l.counts[appId] >= l.DailyQuota {
+			l.mu.Unlock()
+			return nil, ErrQuotaExceeded
+		}
+		l.counts[appId]++
+	}
+	l.mu.Unlock()
+
+	if err := l.wait(ctx); err != nil {
+		if appId != 0 {
+			l.mu.Lock()
+			if l.today() == day && l.counts[appId] > 0 {
+				l.counts[appId]--
+			}
+			l.mu.Unlock()
+		}
+		return nil, err
+	}
+
+	if l.sem == nil {
+		return func() {}, nil
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-l.sem })
+	}, nil
+}
+
+// wait waits for a slot of in-flight requests and then for the rate.
+// The next request time is advanced only when the wait succeeds, so that
+// callers whose ctx is done do not delay the others.
+func (l *Limiter) wait(ctx context.Context) error {
+	if l.sem != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case l.sem <- struct{}{}:
+		}
+	}
+	for l.interval > 0 {
+		l.mu.Lock()
+		now := l.clock()
+		wait := l.next.Sub(now)
+		if wait <= 0 {
+			l.next = now.Add(l.interval)
+			l.mu.Unlock()
+			break
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if l.sem != nil {
+				<-l.sem
+			}
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterInFlight(t *testing.T) {
+	l := NewLimiter(2, 0)
+	var inFlight, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.acquire(context.Background(), 1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			release()
+		}()
+	}
+	wg.Wait()
+	if peak > 2 {
+		t.Errorf("too many requests in flight: %d", peak)
+	}
+	if l.Count(1) != 10 {
+		t.Errorf("unexpected count: %d", l.Count(1))
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	l := NewLimiter(0, 200)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		release, err := l.acquire(context.Background(), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if d := time.Since(start); d < time.Millisecond*20 {
+		t.Errorf("requests were not throttled: %v", d)
+	}
+
+	l = NewLimiter(1, 0)
+	release, _ := l.acquire(context.Background(), 0)
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, 0); err != context.DeadlineExceeded {
+		t.Errorf("acquire must fail with the context error: %v", err)
+	}
+}
+
+func TestLimiterDailyQuota(t *testing.T) {
+	now := time.Date(2019, 3, 11, 23, 59, 0, 0, time.UTC)
+	l := NewLimiter(0, 0)
+	l.DailyQuota = 2
+	l.Location = time.UTC
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := l.acquire(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := l.acquire(context.Background(), 1); err != ErrQuotaExceeded {
+		t.Errorf("acquire must fail with ErrQuotaExceeded: %v", err)
+	}
+	if l.Remaining(1) != 0 || l.Remaining(2) != 2 {
+		t.Errorf("unexpected remaining: %d %d", l.Remaining(1), l.Remaining(2))
+	}
+
+	now = now.Add(time.Minute)
+	if l.Count(1) != 0 {
+		t.Errorf("counts must be reset on the next day: %d", l.Count(1))
+	}
+	if _, err := l.acquire(context.Background(), 1); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLimiterDailyQuotaConcurrent(t *testing.T) {
+	l := NewLimiter(3, 0)
+	l.DailyQuota = 20
+	var ok, exceeded int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.acquire(context.Background(), 1)
+			switch err {
+			case nil:
+				atomic.AddInt32(&ok, 1)
+				time.Sleep(time.Millisecond)
+				release()
+			case ErrQuotaExceeded:
+				atomic.AddInt32(&exceeded, 1)
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if ok != 20 || exceeded != 30 || l.Count(1) != 20 {
+		t.Errorf("unexpected results: %d %d %d", ok, exceeded, l.Count(1))
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	l := NewLimiter(1, 10)
+	l.DailyQuota = 5
+	release, err := l.acquire(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("acquire must fail with the context error: %v", err)
+	}
+	if l.Count(1) != 1 {
+		t.Errorf("canceled requests must not be counted: %d", l.Count(1))
+	}
+	release()
+
+	// A canceled wait for the rate must not delay the next request.
+	next := l.next
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("acquire must fail with the context error: %v", err)
+	}
+	if !l.next.Equal(next) || l.Count(1) != 1 {
+		t.Errorf("canceled requests must not take a slot: %v %v %d", next, l.next, l.Count(1))
+	}
+}
+
+func TestAppLimiter(t *testing.T) {
+	app := newApp()
+	app.Limiter = NewLimiter(1, 0)
+	app.Limiter.DailyQuota = 2
+	for i := 0; i < 2; i++ {
+		if _, err := app.GetRecord(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := app.GetRecord(1); err != ErrQuotaExceeded {
+		t.Errorf("GetRecord must fail with ErrQuotaExceeded: %v", err)
+	}
+	if app.Limiter.Count(KINTONE_APP_ID) != 2 {
+		t.Errorf("unexpected count: %d", app.Limiter.Count(KINTONE_APP_ID))
+	}
+}
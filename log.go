@@ -0,0 +1,173 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// REDACTED replaces credentials in logs.
+const REDACTED = "[REDACTED]"
+
+// maxLoggedBody is the maximum number of bytes of a response body logged.
+// Records are decoded while being read, so their responses are not held
+// in memory for logs either.
+const maxLoggedBody = 64 << 10
+
+// Headers which carry credentials, including the session cookie of
+// password authentication.
+var credentialHeaders = []string{
+	"X-Cybozu-Authorization",
+	"X-Cybozu-API-Token",
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// redactHeader returns a copy of h whose credentials are redacted.
+func redactHeader(h http.Header) http.Header {
+	c := h.Clone()
+	for _, k := range credentialHeaders {
+		if len(c.Values(k)) > 0 {
+			c.Set(k, REDACTED)
+		}
+	}
+	return c
+}
+
+// Query parameters which may carry credentials.
+var credentialParams = []string{
+	"token",
+	"api_token",
+	"access_token",
+	"password",
+}
+
+// redactURL returns u whose password and credential query parameters
+// are redacted.
+func redactURL(u *url.URL) string {
+	c := *u
+	q := c.Query()
+	redacted := false
+	for _, k := range credentialParams {
+		if q.Has(k) {
+			q.Set(k, REDACTED)
+			redacted = true
+		}
+	}
+	if redacted {
+		c.RawQuery = q.Encode()
+	}
+	return c.Redacted()
+}
+
+func (app *App) debugEnabled(ctx context.Context) bool {
+	return app.Logger != nil && app.Logger.Enabled(ctx, slog.LevelDebug)
+}
+
+// logCall logs the outcome of an API call.
+func (app *App) logCall(ctx context.Context, call *APICall, result *APIResult, err error, d time.Duration) {
+	if app.Logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("api", call.API),
+		slog.String("method", call.Method),
+		slog.Uint64("app", call.AppId),
+		slog.Duration("duration", d),
+	}
+	var ae *AppError
+	switch {
+	case err == nil:
+		attrs = append(attrs, slog.Int("status", result.StatusCode))
+		app.Logger.LogAttrs(ctx, slog.LevelInfo, "kintone: API call", attrs...)
+	case errors.As(err, &ae):
+		attrs = append(attrs,
+			slog.Int("status", ae.HttpStatusCode),
+			slog.String("code", ae.Code),
+			slog.String("error", ae.Error()))
+		app.Logger.LogAttrs(ctx, slog.LevelError, "kintone: API call failed", attrs...)
+	default:
+		attrs = append(attrs, slog.String("error", err.Error()))
+		app.Logger.LogAttrs(ctx, slog.LevelError, "kintone: API call failed", attrs...)
+	}
+}
+
+// logRequest dumps req at the debug level.
+func (app *App) logRequest(req *http.Request) {
+	ctx := req.Context()
+	if !app.debugEnabled(ctx) {
+		return
+	}
+	body := ""
+	if req.GetBody != nil && isJSON(req.Header.Get("Content-Type")) {
+		if rc, err := req.GetBody(); err == nil {
+			b, _ := io.ReadAll(rc)
+			rc.Close()
+			body = string(b)
+		}
+	}
+	app.Logger.DebugContext(ctx, "kintone: request",
+		"method", req.Method,
+		"url", redactURL(req.URL),
+		"header", redactHeader(req.Header),
+		"body", body)
+}
+
+// logResponse dumps resp at the debug level without consuming its body.
+func (app *App) logResponse(req *http.Request, resp *http.Response) {
+	ctx := req.Context()
+	if !app.debugEnabled(ctx) {
+		return
+	}
+	body := ""
+	if isJSON(resp.Header.Get("Content-Type")) {
+		body = string(peekBodyPrefix(resp, maxLoggedBody))
+	}
+	app.Logger.DebugContext(ctx, "kintone: response",
+		"method", req.Method,
+		"url", redactURL(req.URL),
+		"status", resp.StatusCode,
+		"header", redactHeader(resp.Header),
+		"body", body)
+}
+
+// logRetry logs a retry of req.
+func (app *App) logRetry(req *http.Request, resp *http.Response, err error, attempt int, delay time.Duration) {
+	if app.Logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", redactURL(req.URL)),
+		slog.Int("attempt", attempt),
+		slog.Duration("delay", delay),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	} else {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	app.Logger.LogAttrs(req.Context(), slog.LevelWarn, "kintone: retrying request", attrs...)
+}
+
+// peekBodyPrefix reads up to n bytes of the body of resp and puts them
+// back so that the whole body can still be read.
+func peekBodyPrefix(resp *http.Response, n int64) []byte {
+	prefix, _ := io.ReadAll(io.LimitReader(resp.Body, n))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), resp.Body), resp.Body}
+	return prefix
+}
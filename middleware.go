@@ -0,0 +1,142 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// APICall describes a logical kintone API call passed to middlewares.
+type APICall struct {
+	API         string      // API name such as "records", "record/status" or "file".
+	Method      string      // HTTP method.
+	AppId       uint64      // ID of the application; 0 for domain-wide APIs.
+	Query       string      // URL query string, if any.
+	Body        interface{} // Request body before JSON encoding, or an io.Reader sent as is.
+	ContentType string      // Content type of Body if it is an io.Reader.
+	Header      http.Header // Additional request headers.
+	stream      bool        // true to return the response body unread.
+}
+
+// APIResult is the result of an APICall.
+type APIResult struct {
+	StatusCode int           // HTTP status code.
+	Header     http.Header   // Response headers.
+	Body       []byte        // Response body.  nil if Stream is set.
+	Stream     io.ReadCloser // Response body of streamed APIs such as file downloads.
+}
+
+// APIHandler processes an APICall.
+//
+// An error response from kintone is returned as *AppError.
+type APIHandler func(ctx context.Context, call *APICall) (*APIResult, error)
+
+// Middleware wraps an APIHandler to observe, modify or short-circuit
+// API calls.
+//
+// ex: add a tracing header and log errors
+//
+//	func trace(next kintone.APIHandler) kintone.APIHandler {
+//		return func(ctx context.Context, call *kintone.APICall) (*kintone.APIResult, error) {
+//			call.Header.Set("X-Request-Id", requestID(ctx))
+//			result, err := next(ctx, call)
+//			if err != nil {
+//				log.Printf("%s %s: %v", call.Method, call.API, err)
+//			}
+//			return result, err
+//		}
+//	}
+//
+//	app.Middlewares = []kintone.Middleware{trace}
+type Middleware func(next APIHandler) APIHandler
+
+// invoke passes call through app.Middlewares to app.roundTrip.
+// The first middleware is the outermost one.
+func (app *App) invoke(ctx context.Context, call *APICall) (*APIResult, error) {
+	if call.Header == nil {
+		call.Header = make(http.Header)
+	}
+	h := app.roundTrip
+	for i := len(app.Middlewares) - 1; i >= 0; i-- {
+		h = app.Middlewares[i](h)
+	}
+	result, err := h(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, ErrInvalidResponse
+	}
+	if call.stream && result.Stream == nil {
+		result.Stream = ioutil.NopCloser(bytes.NewReader(result.Body))
+	}
+	return result, nil
+}
+
+// call sends a JSON API request and returns the response body.
+func (app *App) call(ctx context.Context, method, api string, body interface{}) ([]byte, error) {
+	result, err := app.invoke(ctx, &APICall{
+		API:    api,
+		Method: method,
+		AppId:  app.AppId,
+		Body:   body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Body, nil
+}
+
+// roundTrip is the innermost APIHandler which sends call to kintone.
+func (app *App) roundTrip(ctx context.Context, call *APICall) (result *APIResult, err error) {
+	start := time.Now()
+	defer func() {
+		app.logCall(ctx, call, result, err, time.Since(start))
+	}()
+
+	var body io.Reader
+	switch b := call.Body.(type) {
+	case nil:
+	case io.Reader:
+		body = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := app.newRequest(ctx, call.Method, call.API, call.Query, body)
+	if err != nil {
+		return nil, err
+	}
+	if len(call.ContentType) > 0 {
+		req.Header.Set("Content-Type", call.ContentType)
+	}
+	for k, v := range call.Header {
+		req.Header[k] = v
+	}
+	resp, err := app.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result = &APIResult{StatusCode: resp.StatusCode, Header: resp.Header}
+	if call.stream && resp.StatusCode == http.StatusOK {
+		result.Stream = resp.Body
+		return result, nil
+	}
+	if result.Body, err = parseResponse(resp); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
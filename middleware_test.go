@@ -0,0 +1,143 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var trace []string
+	mark := func(name string) Middleware {
+		return func(next APIHandler) APIHandler {
+			return func(ctx context.Context, call *APICall) (*APIResult, error) {
+				trace = append(trace, name+">"+call.API)
+				result, err := next(ctx, call)
+				trace = append(trace, name+"<")
+				return result, err
+			}
+		}
+	}
+
+	app := newApp()
+	app.Middlewares = []Middleware{mark("a"), mark("b")}
+	if _, err := app.GetRecord(1); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"a>record", "b>record", "b<", "a<"}
+	if !reflect.DeepEqual(trace, expected) {
+		t.Errorf("unexpected trace: %v", trace)
+	}
+}
+
+func TestMiddlewareObserve(t *testing.T) {
+	var calls []*APICall
+	var statuses []int
+	observe := func(next APIHandler) APIHandler {
+		return func(ctx context.Context, call *APICall) (*APIResult, error) {
+			calls = append(calls, call)
+			result, err := next(ctx, call)
+			if err == nil {
+				statuses = append(statuses, result.StatusCode)
+			}
+			return result, err
+		}
+	}
+
+	app := newApp()
+	app.Middlewares = []Middleware{observe}
+	if err := app.DeleteRecords([]uint64{6, 7}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.GetRecordsByCursor("abc"); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || calls[0].API != "records" || calls[0].Method != "DELETE" {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+	if calls[0].AppId != KINTONE_APP_ID || calls[0].Body == nil {
+		t.Errorf("middlewares must see the request body: %+v", calls[0])
+	}
+	if calls[1].API != "records/cursor" || calls[1].Query != "id=abc" {
+		t.Errorf("unexpected call: %+v", calls[1])
+	}
+	if !reflect.DeepEqual(statuses, []int{http.StatusOK, http.StatusOK}) {
+		t.Errorf("unexpected statuses: %v", statuses)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	errDenied := errors.New("denied")
+	stub := func(next APIHandler) APIHandler {
+		return func(ctx context.Context, call *APICall) (*APIResult, error) {
+			switch call.API {
+			case "record":
+				return &APIResult{
+					StatusCode: http.StatusOK,
+					Body:       []byte(`{"record":{"$id":{"type":"__ID__","value":"42"}}}`),
+				}, nil
+			case "file":
+				return &APIResult{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": {"text/plain"}},
+					Body:       []byte("hello"),
+				}, nil
+			}
+			return nil, errDenied
+		}
+	}
+
+	app := newApp()
+	app.BaseURL = "http://127.0.0.1:1" // never reached
+	app.Middlewares = []Middleware{stub}
+	rec, err := app.GetRecord(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Id() != 42 {
+		t.Errorf("unexpected record id: %d", rec.Id())
+	}
+
+	fd, err := app.Download("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadAll(fd.Reader); string(data) != "hello" || fd.ContentType != "text/plain" {
+		t.Errorf("unexpected file: %s %q", fd.ContentType, data)
+	}
+
+	if _, err := app.GetRecords(nil, ""); err != errDenied {
+		t.Errorf("middleware error must be returned: %v", err)
+	}
+}
+
+func TestMiddlewareModify(t *testing.T) {
+	var header string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/k/v1/record.json", func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Trace-Id")
+		handleResponseGetRecord(w, r)
+	})
+	app := newTestApp(t, mux)
+	app.Middlewares = []Middleware{
+		func(next APIHandler) APIHandler {
+			return func(ctx context.Context, call *APICall) (*APIResult, error) {
+				call.Header.Set("X-Trace-Id", "trace-1")
+				return next(ctx, call)
+			}
+		},
+	}
+	if _, err := app.GetRecord(1); err != nil {
+		t.Fatal(err)
+	}
+	if header != "trace-1" {
+		t.Errorf("header was not added: %q", header)
+	}
+}
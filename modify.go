@@ -0,0 +1,94 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DEFAULT_MODIFY_ATTEMPTS is used by ModifyRecord when maxAttempts is not positive.
+const DEFAULT_MODIFY_ATTEMPTS = 5
+
+// ModifyRecord updates a record by read-modify-write with optimistic
+// concurrency control.
+//
+// The record is fetched, passed to mutate, and updated with the fetched
+// revision.  If another client updated the record in the meantime, i.e.
+// the update fails with ErrRevisionConflict, the steps are repeated up to
+// maxAttempts times in total.  An error returned by mutate aborts the
+// process without updating the record.
+//
+// The updated record is returned with its new revision, so that it can
+// be updated again.
+//
+// ex: increment a counter
+//
+//	rec, err := app.ModifyRecord(id, 0, func(rec *kintone.Record) error {
+//		n, err := rec.Int("count")
+//		if err != nil {
+//			return err
+//		}
+//		return rec.SetInt("count", n+1)
+//	})
+func (app *App) ModifyRecord(id uint64, maxAttempts int, mutate func(rec *Record) error) (*Record, error) {
+	return app.ModifyRecordContext(context.Background(), id, maxAttempts, mutate)
+}
+
+// ModifyRecordContext is like ModifyRecord but uses ctx for the API requests.
+func (app *App) ModifyRecordContext(ctx context.Context, id uint64, maxAttempts int, mutate func(rec *Record) error) (*Record, error) {
+	return app.modifyRecord(ctx, maxAttempts, mutate, func() (*Record, error) {
+		return app.GetRecordContext(ctx, id)
+	})
+}
+
+// ModifyRecordByKey is like ModifyRecord but identifies the record by
+// the value of a unique key field.
+//
+// ErrRecordNotFound is returned if no record has the value.
+func (app *App) ModifyRecordByKey(keyField, key string, maxAttempts int, mutate func(rec *Record) error) (*Record, error) {
+	return app.ModifyRecordByKeyContext(context.Background(), keyField, key, maxAttempts, mutate)
+}
+
+// ModifyRecordByKeyContext is like ModifyRecordByKey but uses ctx for the API requests.
+func (app *App) ModifyRecordByKeyContext(ctx context.Context, keyField, key string, maxAttempts int, mutate func(rec *Record) error) (*Record, error) {
+	query := fmt.Sprintf(`%s = "%s" limit 1`, keyField, escapeQuotes(key))
+	return app.modifyRecord(ctx, maxAttempts, mutate, func() (*Record, error) {
+		recs, err := app.GetRecordsContext(ctx, nil, query)
+		if err != nil {
+			return nil, err
+		}
+		if len(recs) == 0 {
+			return nil, ErrRecordNotFound
+		}
+		return recs[0], nil
+	})
+}
+
+func (app *App) modifyRecord(ctx context.Context, maxAttempts int, mutate func(rec *Record) error, get func() (*Record, error)) (*Record, error) {
+	if maxAttempts <= 0 {
+		maxAttempts = DEFAULT_MODIFY_ATTEMPTS
+	}
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var rec *Record
+		if rec, err = get(); err != nil {
+			return nil, err
+		}
+		if err = mutate(rec); err != nil {
+			return nil, err
+		}
+		var result *RecordResult
+		if result, err = app.UpdateRecordWithResultContext(ctx, rec, false); err == nil {
+			syncRecord(rec, *result)
+			return rec, nil
+		}
+		if !errors.Is(err, ErrRevisionConflict) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,130 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newModifyTestApp returns an app whose record 3 is updated by another
+// client before each of the first conflicts updates.
+func newModifyTestApp(t *testing.T, conflicts int) (*App, *requestLog) {
+	var mu sync.Mutex
+	revision, count := 1, 10
+	puts := &requestLog{}
+	app := newTestApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		record := fmt.Sprintf(`{"$id":{"type":"__ID__","value":"3"},`+
+			`"$revision":{"type":"__REVISION__","value":"%d"},`+
+			`"count":{"type":"NUMBER","value":"%d"}}`, revision, count)
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/k/v1/record.json":
+			fmt.Fprintf(w, `{"record":%s}`, record)
+		case r.Method == "GET":
+			if b, _ := ioutil.ReadAll(r.Body); strings.Contains(string(b), `code = \"none\" limit 1`) {
+				fmt.Fprint(w, `{"records":[]}`)
+			} else {
+				fmt.Fprintf(w, `{"records":[%s]}`, record)
+			}
+		case r.Method == "PUT":
+			b := puts.addBody(r)
+			var body struct {
+				Revision int `json:"revision,string"`
+				Record   struct {
+					Count struct {
+						Value int `json:"value,string"`
+					} `json:"count"`
+				} `json:"record"`
+			}
+			json.Unmarshal(b, &body)
+			if conflicts > 0 {
+				conflicts--
+				revision, count = revision+1, count+1
+			}
+			if body.Revision != revision {
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprint(w, GetTestDataRevisionConflict().output)
+				return
+			}
+			revision, count = revision+1, body.Record.Count.Value
+			fmt.Fprintf(w, `{"revision":"%d"}`, revision)
+		}
+	}))
+	return app, puts
+}
+
+func increment(rec *Record) error {
+	n, err := rec.Int("count")
+	if err != nil {
+		return err
+	}
+	return rec.SetInt("count", n+1)
+}
+
+func TestModifyRecord(t *testing.T) {
+	app, puts := newModifyTestApp(t, 2)
+	rec, err := app.ModifyRecord(3, 0, increment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := rec.Int("count"); n != 13 {
+		t.Errorf("unexpected count: %d", n)
+	}
+	if rec.Revision() != 4 {
+		t.Errorf("unexpected revision: %d", rec.Revision())
+	}
+	expected := []string{
+		`{"app":"1","id":"3","revision":"1","record":{"count":{"type":"NUMBER","value":"11"}}}`,
+		`{"app":"1","id":"3","revision":"2","record":{"count":{"type":"NUMBER","value":"12"}}}`,
+		`{"app":"1","id":"3","revision":"3","record":{"count":{"type":"NUMBER","value":"13"}}}`,
+	}
+	got := puts.all()
+	if len(got) != len(expected) {
+		t.Fatalf("unexpected requests: %v", got)
+	}
+	for i, s := range expected {
+		if got[i] != s {
+			t.Errorf("unexpected request: %s", got[i])
+		}
+	}
+}
+
+func TestModifyRecordGiveUp(t *testing.T) {
+	app, puts := newModifyTestApp(t, 5)
+	if _, err := app.ModifyRecord(3, 2, increment); !errors.Is(err, ErrRevisionConflict) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(puts.all()) != 2 {
+		t.Errorf("unexpected requests: %v", puts.all())
+	}
+
+	errAbort := errors.New("abort")
+	_, err := app.ModifyRecord(3, 0, func(rec *Record) error { return errAbort })
+	if err != errAbort || len(puts.all()) != 2 {
+		t.Errorf("mutate must abort: %v", err)
+	}
+}
+
+func TestModifyRecordByKey(t *testing.T) {
+	app, puts := newModifyTestApp(t, 1)
+	if _, err := app.ModifyRecordByKey("code", "A-1", 0, increment); err != nil {
+		t.Fatal(err)
+	}
+	if len(puts.all()) != 2 {
+		t.Errorf("unexpected requests: %v", puts.all())
+	}
+	if _, err := app.ModifyRecordByKey("code", "none", 0, increment); err != ErrRecordNotFound {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// RecordReader decodes the records of a multi-get or cursor response
+// one at a time while reading the response.
+//
+// Unlike DecodeRecords, it does not read the whole response into
+// memory; only the record being decoded is held at a time.  Records are
+// decoded the same way as DecodeRecords does.
+//
+// ex:
+//
+//	rr := kintone.NewRecordReader(resp.Body)
+//	for rr.Next() {
+//		process(rr.Record())
+//	}
+//	if err := rr.Err(); err != nil {
+//		return err
+//	}
+type RecordReader struct {
+	d          *Decoder
+	dec        *json.Decoder
+	started    bool
+	done       bool
+	rec        *Record
+	index      int
+	errs       []*FieldDecodeError
+	err        error
+	totalCount string
+	next       bool
+}
+
+// NewRecordReader returns a RecordReader which decodes records from r.
+func (d *Decoder) NewRecordReader(r io.Reader) *RecordReader {
+	return &RecordReader{d: d, dec: json.NewDecoder(r)}
+}
+
+// NewRecordReader returns a strict RecordReader which decodes records from r.
+func NewRecordReader(r io.Reader) *RecordReader {
+	return (&Decoder{Logger: slog.Default()}).NewRecordReader(r)
+}
+
+// Next decodes the next record.  It returns false at the end of the
+// records or on an error.
+//
+// A strict reader stops at the first record which has a field error.
+// A lenient one keeps going and Err reports the field errors after all
+// records are read.
+func (rr *RecordReader) Next() bool {
+	rr.rec = nil
+	if rr.done || rr.err != nil {
+		return false
+	}
+	if !rr.started {
+		rr.started = true
+		if err := rr.start(); err != nil {
+			rr.fail(err)
+			return false
+		}
+		if rr.done {
+			return false
+		}
+	}
+	if !rr.dec.More() {
+		if err := rr.finish(); err != nil {
+			rr.fail(err)
+		}
+		return false
+	}
+
+	var rd recordData
+	if err := rr.dec.Decode(&rd); err != nil {
+		rr.fail(err)
+		return false
+	}
+	rec, errs := rr.d.decodeRecordData(rd)
+	for _, e := range errs {
+		e.RecordIndex = rr.index
+	}
+	rr.index++
+	if len(errs) > 0 {
+		if ok, err := rr.d.result(errs); !ok {
+			rr.err = err
+			return false
+		}
+		rr.errs = append(rr.errs, errs...)
+	}
+	rec.takeSnapshot()
+	rr.rec = rec
+	return true
+}
+
+// Record returns the record decoded by the last call to Next.
+func (rr *RecordReader) Record() *Record {
+	return rr.rec
+}
+
+// Err returns the error which stopped the reader, if any.
+//
+// Malformed responses are reported as ErrInvalidResponse.  A lenient
+// reader returns *DecodeError at the end if some fields were skipped.
+func (rr *RecordReader) Err() error {
+	return rr.err
+}
+
+// TotalCount returns the totalCount property of the response.  It is
+// available once Next has returned false unless kintone sends it before
+// the records.
+func (rr *RecordReader) TotalCount() string {
+	return rr.totalCount
+}
+
+// HasNext returns the next property of cursor responses, which is true
+// if the cursor has more records.  It is available once Next has
+// returned false.
+func (rr *RecordReader) HasNext() bool {
+	return rr.next
+}
+
+// fail stops rr with err.  Malformed JSON is reported as
+// ErrInvalidResponse while errors of the underlying reader are kept.
+func (rr *RecordReader) fail(err error) {
+	switch err.(type) {
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		err = ErrInvalidResponse
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = ErrInvalidResponse
+	}
+	rr.err = err
+}
+
+// start reads the response up to the beginning of the records.
+func (rr *RecordReader) start() error {
+	tok, err := rr.dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+	case nil:
+		rr.done = true
+		return nil
+	default:
+		return ErrInvalidResponse
+	}
+	for rr.dec.More() {
+		key, err := rr.readProperty()
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(key, "records") {
+			continue
+		}
+		tok, err = rr.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('['):
+			return nil
+		case nil:
+		default:
+			return ErrInvalidResponse
+		}
+	}
+	rr.done = true
+	_, err = rr.dec.Token()
+	return err
+}
+
+// finish reads the rest of the response after the records.
+func (rr *RecordReader) finish() error {
+	rr.done = true
+	if _, err := rr.dec.Token(); err != nil {
+		return err
+	}
+	for rr.dec.More() {
+		key, err := rr.readProperty()
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(key, "records") {
+			if err := skipValue(rr.dec); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := rr.dec.Token(); err != nil {
+		return err
+	}
+	if len(rr.errs) > 0 {
+		_, rr.err = rr.d.result(rr.errs)
+	}
+	return nil
+}
+
+// readProperty reads a property name of the response.  Properties other
+// than records are decoded or skipped here.
+func (rr *RecordReader) readProperty() (string, error) {
+	key, err := readKey(rr.dec)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case strings.EqualFold(key, "records"):
+		return key, nil
+	case strings.EqualFold(key, "totalCount"):
+		err = rr.dec.Decode(&rr.totalCount)
+	case strings.EqualFold(key, "next"):
+		err = rr.dec.Decode(&rr.next)
+	default:
+		err = skipValue(rr.dec)
+	}
+	return key, err
+}
+
+// readKey reads a property name.  Names are compared with
+// strings.EqualFold as encoding/json does.
+func readKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", ErrInvalidResponse
+	}
+	return key, nil
+}
+
+// skipValue skips the next value.
+func skipValue(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	return skipRest(dec, tok)
+}
+
+// skipRest skips the rest of a value whose first token is tok.
+func skipRest(dec *json.Decoder, tok json.Token) error {
+	if tok != json.Delim('{') && tok != json.Delim('[') {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
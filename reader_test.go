@@ -0,0 +1,176 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// recordsJSON returns a multi-get response of n records, each of which
+// has a subtable of rows rows.
+func recordsJSON(n, rows int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"records":[`)
+	for i := 1; i <= n; i++ {
+		if i > 1 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"$id":{"type":"__ID__","value":"%d"},`+
+			`"$revision":{"type":"__REVISION__","value":"3"},`+
+			`"title":{"type":"SINGLE_LINE_TEXT","value":"record %d"},`+
+			`"price":{"type":"NUMBER","value":"%d.5"},`+
+			`"date":{"type":"DATE","value":"2019-03-11"},`+
+			`"tags":{"type":"CHECK_BOX","value":["a","b"]},`+
+			`"owner":{"type":"CREATOR","value":{"code":"sato","name":"Noboru Sato"}},`+
+			`"ctime":{"type":"CREATED_TIME","value":"2012-02-03T08:50:00Z"},`+
+			`"table":{"type":"SUBTABLE","value":[`, i, i, i)
+		for j := 1; j <= rows; j++ {
+			if j > 1 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, `{"id":"%d","value":{`+
+				`"item":{"type":"SINGLE_LINE_TEXT","value":"item %d"},`+
+				`"qty":{"type":"NUMBER","value":"%d"},`+
+				`"users":{"type":"USER_SELECT","value":[{"code":"sato","name":"Noboru Sato"}]}}}`, i*1000+j, j, j)
+		}
+		b.WriteString(`]}}`)
+	}
+	b.WriteString(`],"totalCount":"123","next":true}`)
+	return b.Bytes()
+}
+
+func readAll(rr *RecordReader) []*Record {
+	var recs []*Record
+	for rr.Next() {
+		recs = append(recs, rr.Record())
+	}
+	return recs
+}
+
+func TestRecordReader(t *testing.T) {
+	t.Parallel()
+
+	b := recordsJSON(20, 3)
+	expected, err := DecodeRecords(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := NewRecordReader(iotest.OneByteReader(bytes.NewReader(b)))
+	recs := readAll(rr)
+	if err := rr.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(recs, expected) {
+		t.Error("records must be decoded as DecodeRecords does")
+	}
+	if rr.TotalCount() != "123" || !rr.HasNext() {
+		t.Errorf("unexpected properties: %q %v", rr.TotalCount(), rr.HasNext())
+	}
+
+	for _, s := range []string{`{}`, `{"records":null,"totalCount":"0"}`, `{"records":[]}`} {
+		rr := NewRecordReader(strings.NewReader(s))
+		if rr.Next() || rr.Err() != nil {
+			t.Errorf("%s: unexpected result: %v", s, rr.Err())
+		}
+	}
+	for _, s := range []string{``, `[]`, `{"records":{}}`, `{"records":[1]}`, `{"records":[{}`} {
+		rr := NewRecordReader(strings.NewReader(s))
+		if readAll(rr); rr.Err() != ErrInvalidResponse {
+			t.Errorf("%s: unexpected error: %v", s, rr.Err())
+		}
+	}
+
+	errRead := errors.New("read")
+	rr = NewRecordReader(io.MultiReader(bytes.NewReader(b[:100]), iotest.ErrReader(errRead)))
+	if readAll(rr); rr.Err() != errRead {
+		t.Errorf("unexpected error: %v", rr.Err())
+	}
+}
+
+func TestRecordReaderErrors(t *testing.T) {
+	t.Parallel()
+
+	j := strings.Replace(brokenRecordJSON, `"record": {`, `"records": [{"title": {"type": "SINGLE_LINE_TEXT", "value": "a"}}, {`, 1)
+	j = strings.Replace(j, "}\n}", "}]\n}", 1)
+
+	rr := NewRecordReader(strings.NewReader(j))
+	recs := readAll(rr)
+	var fe *FieldDecodeError
+	if len(recs) != 1 || !errors.As(rr.Err(), &fe) || fe.RecordIndex != 1 || fe.FieldCode != "broken" {
+		t.Errorf("unexpected result: %d %v", len(recs), rr.Err())
+	}
+
+	d := &Decoder{Lenient: true}
+	expected, expectedErr := d.DecodeRecords([]byte(j))
+	rr = d.NewRecordReader(strings.NewReader(j))
+	recs = readAll(rr)
+	if !reflect.DeepEqual(recs, expected) {
+		t.Error("records must be decoded as DecodeRecords does")
+	}
+	if fmt.Sprint(rr.Err()) != fmt.Sprint(expectedErr) {
+		t.Errorf("unexpected error: %v", rr.Err())
+	}
+}
+
+func FuzzRecordReader(f *testing.F) {
+	f.Add(recordsJSON(2, 2))
+	f.Add([]byte(`{"records": [{"a": {"value": [{"id": "1", "value": null}], "type": "SUBTABLE"}}]}`))
+	f.Add([]byte(`{"records": [{"a": {"type": "SUBTABLE", "value": [{"id": "1", "value": {"b": 1}}]}}]}`))
+	f.Add([]byte(`{"records": [null, {"a": {"type": "__REVISION__", "value": "x"}}]}`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		expected, err := DecodeRecords(b)
+		rr := NewRecordReader(bytes.NewReader(b))
+		recs := readAll(rr)
+		if err != nil {
+			return
+		}
+		if rr.Err() != nil {
+			t.Fatalf("unexpected error: %v", rr.Err())
+		}
+		if len(recs) != len(expected) {
+			t.Fatalf("unexpected records: %d", len(recs))
+		}
+		// IDs may differ if a record has more than one ID field as
+		// DecodeRecords decodes fields in random order.
+		for i, rec := range recs {
+			if !reflect.DeepEqual(rec.Fields, expected[i].Fields) {
+				t.Errorf("fields must be decoded as DecodeRecords does: %v", rec.Fields)
+			}
+		}
+	})
+}
+
+func BenchmarkDecodeRecords(b *testing.B) {
+	data := recordsJSON(500, 20)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeRecords(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRecordReader(b *testing.B) {
+	data := recordsJSON(500, 20)
+	d := &Decoder{}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rr := d.NewRecordReader(bytes.NewReader(data))
+		for rr.Next() {
+		}
+		if err := rr.Err(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -5,10 +5,12 @@
 package kintone
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,99 +20,152 @@ import (
 //
 // Fields is a mapping between field IDs and fields.
 // Although field types are shown as interface{}, they are guaranteed
-// to be one of a *Field type in this package.
+// to be one of a *Field type in this package.  Fields of types unknown
+// to this package are RawField.
+//
+// Records decoded from API responses keep a snapshot of their fields,
+// and updating such records sends only the fields changed since then.
 type Record struct {
 	id       uint64
 	revision int64
 	Fields   map[string]interface{}
+	snapshot map[string][]byte // JSON of the fields when decoded.
 }
 
 // NewRecord creates an instance of Record.
 //
 // The revision number is initialized to -1.
 func NewRecord(fields map[string]interface{}) *Record {
-	return &Record{0, -1, fields}
+	return &Record{0, -1, fields, nil}
 }
 
 // NewRecordWithId creates using an existing record id.
 //
 // The revision number is initialized to -1.
 func NewRecordWithId(id uint64, fields map[string]interface{}) *Record {
-	return &Record{id, -1, fields}
+	return &Record{id, -1, fields, nil}
 }
 
 // NewRecordWithIdAndRevision creates using an existing record id and revision.
 func NewRecordWithIdAndRevision(id uint64, revision int64, fields map[string]interface{}) *Record {
-	return &Record{id, revision, fields}
-
+	return &Record{id, revision, fields, nil}
 }
 
-// MarshalJSON marshals field data of a record into JSON.
-func (rec Record) MarshalJSON() ([]byte, error) {
-	return json.Marshal(rec.Fields)
-}
-
-// Id returns the record number.
+// MarshalJSON marshals a record into JSON in the same format as
+// kintone API responses.
 //
-// A record number is unique within an application.
-func (rec Record) Id() uint64 {
-	return rec.id
+// Along with the fields, the record ID and the revision are encoded as
+// "$id" and "$revision" fields if they are set.  UnmarshalJSON decodes
+// the result into the same record.
+func (rec Record) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(rec.Fields)+2)
+	for k, v := range rec.Fields {
+		m[k] = v
+	}
+	if rec.id != 0 {
+		m["$id"] = map[string]interface{}{
+			"type":  FT_ID,
+			"value": strconv.FormatUint(rec.id, 10),
+		}
+	}
+	if rec.revision >= 0 {
+		m["$revision"] = map[string]interface{}{
+			"type":  FT_REVISION,
+			"value": strconv.FormatInt(rec.revision, 10),
+		}
+	}
+	return json.Marshal(m)
 }
 
-// Revision returns the record revision number.
-func (rec Record) Revision() int64 {
-	return rec.revision
+// UnmarshalJSON decodes a record in the format of kintone API responses.
+func (rec *Record) UnmarshalJSON(b []byte) error {
+	var rd recordData
+	if err := json.Unmarshal(b, &rd); err != nil {
+		return err
+	}
+	d := &Decoder{}
+	r, errs := d.decodeRecordData(rd)
+	if _, err := d.result(errs); err != nil {
+		return err
+	}
+	r.takeSnapshot()
+	*rec = *r
+	return nil
 }
 
-// Assert string list.
-func stringList(l []interface{}) []string {
-	sl := make([]string, len(l))
-	for i, v := range l {
-		sl[i] = v.(string)
+// takeSnapshot records the current fields of rec as unchanged.
+func (rec *Record) takeSnapshot() {
+	rec.snapshot = make(map[string][]byte, len(rec.Fields))
+	for code, f := range rec.Fields {
+		if b, err := json.Marshal(f); err == nil {
+			rec.snapshot[code] = b
+		}
 	}
-	return sl
 }
 
-// Convert user list.
-func userList(l []interface{}) ([]User, error) {
-	b, err := json.Marshal(l)
-	if err != nil {
-		return nil, err
+// isChanged returns true if the field code was modified after the
+// snapshot was taken.
+func (rec *Record) isChanged(code string) bool {
+	if rec.snapshot == nil {
+		return true
 	}
-	var ul []User
-	err = json.Unmarshal(b, &ul)
-	if err != nil {
-		return nil, err
+	old, ok := rec.snapshot[code]
+	if !ok {
+		return true
 	}
-	return ul, nil
+	b, err := json.Marshal(rec.Fields[code])
+	return err != nil || !bytes.Equal(old, b)
 }
 
-// Convert organization list.
-func organizationList(l []interface{}) ([]Organization, error) {
-	b, err := json.Marshal(l)
-	if err != nil {
-		return nil, err
-	}
-	var ol []Organization
-	err = json.Unmarshal(b, &ol)
-	if err != nil {
-		return nil, err
+// ChangedFields returns the codes of fields added or modified since
+// the record was decoded or updated, sorted in ascending order.
+// Every field is reported for records not from API responses.
+func (rec *Record) ChangedFields() []string {
+	var codes []string
+	for code := range rec.Fields {
+		if rec.isChanged(code) {
+			codes = append(codes, code)
+		}
 	}
-	return ol, nil
+	sort.Strings(codes)
+	return codes
 }
 
-// Convert group list.
-func groupList(l []interface{}) ([]Group, error) {
-	b, err := json.Marshal(l)
-	if err != nil {
-		return nil, err
-	}
-	var gl []Group
-	err = json.Unmarshal(b, &gl)
-	if err != nil {
-		return nil, err
+// writableFields returns the fields of rec except for built-in ones.
+// Subtable rows are stripped as well.  If changedOnly is true, only
+// changed fields are returned.
+func (rec *Record) writableFields(changedOnly bool) recordFields {
+	fields := make(recordFields, len(rec.Fields))
+	for code, f := range rec.Fields {
+		if IsBuiltinField(f) || (changedOnly && !rec.isChanged(code)) {
+			continue
+		}
+		if st, ok := f.(SubTableField); ok {
+			rows := make(SubTableField, len(st))
+			for i, row := range st {
+				rows[i] = &Record{row.id, row.revision, map[string]interface{}(row.writableFields(false)), nil}
+			}
+			f = rows
+		}
+		fields[code] = f
 	}
-	return gl, nil
+	return fields
+}
+
+// recordFields is the JSON representation of a record in request
+// bodies, which consists of the fields only.
+type recordFields map[string]interface{}
+
+// Id returns the record number.
+//
+// A record number is unique within an application.
+func (rec Record) Id() uint64 {
+	return rec.id
+}
+
+// Revision returns the record revision number.
+func (rec Record) Revision() int64 {
+	return rec.revision
 }
 
 // Convert string "record number" into an integer.
@@ -126,203 +181,357 @@ func numericId(id string) (uint64, error) {
 	return nid, nil
 }
 
-type recordData map[string]struct {
-	Type  string      `json:"type"`
-	Value interface{} `json:"value"`
+type recordData map[string]json.RawMessage
+
+type fieldData struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// FieldDecodeError reports a field which could not be decoded.
+type FieldDecodeError struct {
+	RecordIndex  int    // Index of the record in multi-get responses, or -1.
+	FieldCode    string // Field code.
+	Row          int    // Row index in the subtable, or -1.
+	SubFieldCode string // Field code in the subtable row.
+	Type         string // Field type such as "NUMBER".
+	Err          error
 }
 
-func decodeRecordData(data recordData) (*Record, error) {
-	fields := make(map[string]interface{})
-	rec := &Record{0, -1, fields}
-	for key, v := range data {
-		switch v.Type {
+func (e *FieldDecodeError) Error() string {
+	name := e.FieldCode
+	if e.Row >= 0 {
+		name = fmt.Sprintf("%s[%d].%s", name, e.Row, e.SubFieldCode)
+	}
+	if e.RecordIndex >= 0 {
+		name = fmt.Sprintf("records[%d].%s", e.RecordIndex, name)
+	}
+	return fmt.Sprintf("kintone: cannot decode %s (%s): %v", name, e.Type, e.Err)
+}
+
+func (e *FieldDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports ErrInvalidResponse as the category of e.
+func (e *FieldDecodeError) Is(target error) bool {
+	return target == ErrInvalidResponse
+}
+
+// DecodeError is returned by a lenient Decoder together with
+// partially decoded records.
+type DecodeError struct {
+	Errors []*FieldDecodeError // Sorted by the record index and the field code.
+}
+
+func (e *DecodeError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	return fmt.Sprintf("%v (and %d more errors)", e.Errors[0], len(e.Errors)-1)
+}
+
+func (e *DecodeError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, fe := range e.Errors {
+		errs[i] = fe
+	}
+	return errs
+}
+
+// Decoder decodes records in JSON responses.
+//
+// A strict Decoder, the default, fails with *FieldDecodeError on the
+// first field which has an unexpected value.  A lenient Decoder skips
+// such fields and returns partially decoded records with *DecodeError.
+type Decoder struct {
+	Lenient bool         // Keep going on field errors.
+	Logger  *slog.Logger // Unknown field types are reported to Logger at the debug level if not nil.
+}
+
+// unmarshalValue is json.Unmarshal which treats a missing value as null.
+func unmarshalValue(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// decodeField decodes the value of a field other than subtables.
+// Record IDs and revisions are set to rec.  It returns nil for fields
+// which are not stored in rec.Fields.
+func (d *Decoder) decodeField(rec *Record, code string, fd fieldData) (interface{}, error) {
+	switch fd.Type {
+	case FT_SINGLE_LINE_TEXT, FT_MULTI_LINE_TEXT, FT_RICH_TEXT, FT_DECIMAL,
+		FT_CALC, FT_RADIO, FT_LINK, FT_STATUS, FT_RECNUM:
+		var s string
+		if err := unmarshalValue(fd.Value, &s); err != nil {
+			return nil, err
+		}
+		switch fd.Type {
 		case FT_SINGLE_LINE_TEXT:
-			fields[key] = SingleLineTextField(v.Value.(string))
+			return SingleLineTextField(s), nil
 		case FT_MULTI_LINE_TEXT:
-			fields[key] = MultiLineTextField(v.Value.(string))
+			return MultiLineTextField(s), nil
 		case FT_RICH_TEXT:
-			fields[key] = RichTextField(v.Value.(string))
+			return RichTextField(s), nil
 		case FT_DECIMAL:
-			fields[key] = DecimalField(v.Value.(string))
+			return DecimalField(s), nil
 		case FT_CALC:
-			fields[key] = CalcField(v.Value.(string))
-		case FT_CHECK_BOX:
-			fields[key] = CheckBoxField(stringList(v.Value.([]interface{})))
+			return CalcField(s), nil
 		case FT_RADIO:
-			if v.Value == nil {
-				fields[key] = RadioButtonField("")
-			} else {
-				fields[key] = RadioButtonField(v.Value.(string))
-			}
-		case FT_SINGLE_SELECT:
-			if v.Value == nil {
-				fields[key] = SingleSelectField{Valid: false}
-			} else {
-				fields[key] = SingleSelectField{v.Value.(string), true}
-			}
-		case FT_MULTI_SELECT:
-			fields[key] = MultiSelectField(stringList(v.Value.([]interface{})))
-		case FT_FILE:
-			b1, err := json.Marshal(v.Value)
-			if err != nil {
-				return nil, err
-			}
-			var fl []File
-			err = json.Unmarshal(b1, &fl)
-			if err != nil {
-				return nil, err
-			}
-			fields[key] = FileField(fl)
+			return RadioButtonField(s), nil
 		case FT_LINK:
-			fields[key] = LinkField(v.Value.(string))
+			return LinkField(s), nil
+		case FT_STATUS:
+			return StatusField(s), nil
+		}
+		nid, err := numericId(s)
+		if err != nil {
+			return nil, err
+		}
+		rec.id = nid
+		return RecordNumberField(s), nil
+	case FT_CHECK_BOX, FT_MULTI_SELECT, FT_CATEGORY:
+		var sl []string
+		if err := unmarshalValue(fd.Value, &sl); err != nil {
+			return nil, err
+		}
+		switch fd.Type {
+		case FT_CHECK_BOX:
+			return CheckBoxField(sl), nil
+		case FT_MULTI_SELECT:
+			return MultiSelectField(sl), nil
+		}
+		return CategoryField(sl), nil
+	case FT_SINGLE_SELECT:
+		var s *string
+		if err := unmarshalValue(fd.Value, &s); err != nil {
+			return nil, err
+		}
+		if s == nil {
+			return SingleSelectField{Valid: false}, nil
+		}
+		return SingleSelectField{*s, true}, nil
+	case FT_FILE:
+		var fl []File
+		if err := unmarshalValue(fd.Value, &fl); err != nil {
+			return nil, err
+		}
+		return FileField(fl), nil
+	case FT_DATE, FT_TIME, FT_DATETIME:
+		var s string
+		if err := unmarshalValue(fd.Value, &s); err != nil {
+			return nil, err
+		}
+		switch fd.Type {
 		case FT_DATE:
-			if v.Value == nil {
-				fields[key] = DateField{Valid: false}
-			} else {
-				d, err := time.Parse("2006-01-02", v.Value.(string))
-				if err != nil {
-					return nil, fmt.Errorf("Invalid date: %v", v.Value)
-				}
-				fields[key] = DateField{d, true}
-			}
-		case FT_TIME:
-			if v.Value == nil {
-				fields[key] = TimeField{Valid: false}
-			} else {
-				t, err := time.Parse("15:04", v.Value.(string))
-				if err != nil {
-					t, err = time.Parse("15:04:05", v.Value.(string))
-					if err != nil {
-						return nil, fmt.Errorf("Invalid time: %v", v.Value)
-					}
-				}
-				fields[key] = TimeField{t, true}
-			}
-		case FT_DATETIME:
-			if v.Value == "" {
-				fields[key] = DateTimeField{Valid: false}
-			} else {
-				if s, ok := v.Value.(string); ok {
-					dt, err := time.Parse(time.RFC3339, s)
-					if err != nil {
-						return nil, fmt.Errorf("Invalid datetime: %v", v.Value)
-					}
-					fields[key] = DateTimeField{dt, true}
-				}
+			if len(s) == 0 {
+				return DateField{Valid: false}, nil
 			}
-		case FT_USER:
-			ul, err := userList(v.Value.([]interface{}))
-			if err != nil {
-				return nil, err
-			}
-			fields[key] = UserField(ul)
-		case FT_ORGANIZATION:
-			ol, err := organizationList(v.Value.([]interface{}))
-			if err != nil {
-				return nil, err
-			}
-			fields[key] = OrganizationField(ol)
-		case FT_GROUP:
-			gl, err := groupList(v.Value.([]interface{}))
-			if err != nil {
-				return nil, err
-			}
-			fields[key] = GroupField(gl)
-		case FT_CATEGORY:
-			fields[key] = CategoryField(stringList(v.Value.([]interface{})))
-		case FT_STATUS:
-			fields[key] = StatusField(v.Value.(string))
-		case FT_ASSIGNEE:
-			al, err := userList(v.Value.([]interface{}))
+			d, err := time.Parse("2006-01-02", s)
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("Invalid date: %v", s)
 			}
-			fields[key] = AssigneeField(al)
-		case FT_RECNUM:
-			if nid, err := numericId(v.Value.(string)); err != nil {
-				return nil, err
-			} else {
-				rec.id = nid
-			}
-			fields[key] = RecordNumberField(v.Value.(string))
-		case FT_CREATOR:
-			creator := v.Value.(map[string]interface{})
-			fields[key] = CreatorField{
-				creator["code"].(string),
-				creator["name"].(string),
-			}
-		case FT_CTIME:
-			var ctime time.Time
-			if ctime.UnmarshalText([]byte(v.Value.(string))) != nil {
-				return nil, fmt.Errorf("Invalid datetime: %v", v.Value)
-			}
-			fields[key] = CreationTimeField(ctime)
-		case FT_MODIFIER:
-			modifier := v.Value.(map[string]interface{})
-			fields[key] = ModifierField{
-				modifier["code"].(string),
-				modifier["name"].(string),
-			}
-		case FT_MTIME:
-			var mtime time.Time
-			if mtime.UnmarshalText([]byte(v.Value.(string))) != nil {
-				return nil, fmt.Errorf("Invalid datetime: %v", v.Value)
-			}
-			fields[key] = ModificationTimeField(mtime)
-		case FT_SUBTABLE:
-			b2, err := json.Marshal(v.Value)
-			if err != nil {
-				return nil, err
+			return DateField{d, true}, nil
+		case FT_TIME:
+			if len(s) == 0 {
+				return TimeField{Valid: false}, nil
 			}
-			var stl []SubTableEntry
-			err = json.Unmarshal(b2, &stl)
+			t, err := time.Parse("15:04", s)
 			if err != nil {
-				return nil, err
-			}
-			ra := make([]*Record, len(stl))
-			for i, sr := range stl {
-				b3, err := json.Marshal(sr.Value)
-				if err != nil {
-					return nil, err
-				}
-				var rd recordData
-				err = json.Unmarshal(b3, &rd)
+				t, err = time.Parse("15:04:05", s)
 				if err != nil {
-					return nil, err
+					return nil, fmt.Errorf("Invalid time: %v", s)
 				}
-				r, err := decodeRecordData(recordData(rd))
-				if err != nil {
-					return nil, err
-				}
-				id, err := strconv.ParseUint(sr.Id, 10, 64)
-				if err != nil {
-					return nil, err
-				}
-				r.id = id
-				ra[i] = r
 			}
-			fields[key] = SubTableField(ra)
-		case FT_ID:
-			id, err := strconv.ParseUint(v.Value.(string), 10, 64)
+			return TimeField{t, true}, nil
+		}
+		if len(s) == 0 {
+			return DateTimeField{Valid: false}, nil
+		}
+		dt, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid datetime: %v", s)
+		}
+		return DateTimeField{dt, true}, nil
+	case FT_USER, FT_ASSIGNEE:
+		var ul []User
+		if err := unmarshalValue(fd.Value, &ul); err != nil {
+			return nil, err
+		}
+		if fd.Type == FT_ASSIGNEE {
+			return AssigneeField(ul), nil
+		}
+		return UserField(ul), nil
+	case FT_ORGANIZATION:
+		var ol []Organization
+		if err := unmarshalValue(fd.Value, &ol); err != nil {
+			return nil, err
+		}
+		return OrganizationField(ol), nil
+	case FT_GROUP:
+		var gl []Group
+		if err := unmarshalValue(fd.Value, &gl); err != nil {
+			return nil, err
+		}
+		return GroupField(gl), nil
+	case FT_CREATOR, FT_MODIFIER:
+		var u User
+		if err := unmarshalValue(fd.Value, &u); err != nil {
+			return nil, err
+		}
+		if fd.Type == FT_MODIFIER {
+			return ModifierField(u), nil
+		}
+		return CreatorField(u), nil
+	case FT_CTIME, FT_MTIME:
+		var s string
+		if err := unmarshalValue(fd.Value, &s); err != nil {
+			return nil, err
+		}
+		var t time.Time
+		if t.UnmarshalText([]byte(s)) != nil {
+			return nil, fmt.Errorf("Invalid datetime: %v", s)
+		}
+		if fd.Type == FT_MTIME {
+			return ModificationTimeField(t), nil
+		}
+		return CreationTimeField(t), nil
+	case FT_ID, FT_REVISION:
+		var s string
+		if err := unmarshalValue(fd.Value, &s); err != nil {
+			return nil, err
+		}
+		if fd.Type == FT_ID {
+			id, err := strconv.ParseUint(s, 10, 64)
 			if err != nil {
-				return nil, fmt.Errorf("Invalid record ID: %v", v.Value)
+				return nil, fmt.Errorf("Invalid record ID: %v", s)
 			}
 			rec.id = id
-		case FT_REVISION:
-			revision, err := strconv.ParseInt(v.Value.(string), 10, 64)
-			if err != nil {
-				return nil, fmt.Errorf("Invalid revision number: %v", v.Value)
+			return nil, nil
+		}
+		revision, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid revision number: %v", s)
+		}
+		rec.revision = revision
+		return nil, nil
+	}
+	if d.Logger != nil {
+		d.Logger.Debug("kintone: unknown field type", "field", code, "type", fd.Type)
+	}
+	return RawField{fd.Type, fd.Value}, nil
+}
+
+// decodeSubTable decodes the rows of a subtable field.
+func (d *Decoder) decodeSubTable(code string, raw json.RawMessage) (SubTableField, []*FieldDecodeError) {
+	var rows []struct {
+		Id    string     `json:"id"`
+		Value recordData `json:"value"`
+	}
+	if err := unmarshalValue(raw, &rows); err != nil {
+		return nil, []*FieldDecodeError{{-1, code, -1, "", FT_SUBTABLE, err}}
+	}
+	var errs []*FieldDecodeError
+	ra := make([]*Record, 0, len(rows))
+	for i, row := range rows {
+		r, rerrs := d.decodeRecordData(row.Value)
+		for _, e := range rerrs {
+			e.Row, e.SubFieldCode, e.FieldCode = i, e.FieldCode, code
+		}
+		errs = append(errs, rerrs...)
+		id, err := strconv.ParseUint(row.Id, 10, 64)
+		if err != nil {
+			errs = append(errs, &FieldDecodeError{-1, code, i, "", FT_SUBTABLE,
+				fmt.Errorf("Invalid row ID: %v", row.Id)})
+			continue
+		}
+		r.id = id
+		ra = append(ra, r)
+	}
+	return SubTableField(ra), errs
+}
+
+// decodeRecordData decodes a record.  Fields which could not be decoded
+// are not set to the record.
+func (d *Decoder) decodeRecordData(data recordData) (*Record, []*FieldDecodeError) {
+	fields := make(map[string]interface{})
+	rec := &Record{0, -1, fields, nil}
+	var errs []*FieldDecodeError
+	for code, raw := range data {
+		var fd fieldData
+		if err := json.Unmarshal(raw, &fd); err != nil {
+			errs = append(errs, &FieldDecodeError{-1, code, -1, "", "", err})
+			continue
+		}
+		if fd.Type == FT_SUBTABLE {
+			st, serrs := d.decodeSubTable(code, fd.Value)
+			errs = append(errs, serrs...)
+			if st != nil {
+				fields[code] = st
 			}
-			rec.revision = revision
-		default:
-			log.Printf("Unknown type: %v", v.Type)
+			continue
+		}
+		v, err := d.decodeField(rec, code, fd)
+		if err != nil {
+			errs = append(errs, &FieldDecodeError{-1, code, -1, "", fd.Type, err})
+			continue
+		}
+		if v != nil {
+			fields[code] = v
 		}
 	}
-	return rec, nil
+	return rec, errs
+}
+
+// result converts field errors into the error returned by d.
+// ok is false if records must be discarded.
+func (d *Decoder) result(errs []*FieldDecodeError) (ok bool, err error) {
+	if len(errs) == 0 {
+		return true, nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		a, b := errs[i], errs[j]
+		if a.RecordIndex != b.RecordIndex {
+			return a.RecordIndex < b.RecordIndex
+		}
+		if a.FieldCode != b.FieldCode {
+			return a.FieldCode < b.FieldCode
+		}
+		if a.Row != b.Row {
+			return a.Row < b.Row
+		}
+		return a.SubFieldCode < b.SubFieldCode
+	})
+	if !d.Lenient {
+		return false, errs[0]
+	}
+	return true, &DecodeError{errs}
+}
+
+// decodeRecordList decodes records of multi-get responses.
+func (d *Decoder) decodeRecordList(rdl []recordData) ([]*Record, error) {
+	var errs []*FieldDecodeError
+	recs := make([]*Record, len(rdl))
+	for i, rd := range rdl {
+		r, rerrs := d.decodeRecordData(rd)
+		for _, e := range rerrs {
+			e.RecordIndex = i
+		}
+		errs = append(errs, rerrs...)
+		r.takeSnapshot()
+		recs[i] = r
+	}
+	ok, err := d.result(errs)
+	if !ok {
+		return nil, err
+	}
+	return recs, err
 }
 
 // DecodeRecords decodes JSON response for multi-get API.
-func DecodeRecords(b []byte) ([]*Record, error) {
+func (d *Decoder) DecodeRecords(b []byte) ([]*Record, error) {
 	var t struct {
 		Records []recordData `json:"records"`
 	}
@@ -330,19 +539,12 @@ func DecodeRecords(b []byte) ([]*Record, error) {
 	if err != nil {
 		return nil, errors.New("Invalid JSON format")
 	}
-
-	rec_list := make([]*Record, len(t.Records))
-	for i, rd := range t.Records {
-		r, err := decodeRecordData(rd)
-		if err != nil {
-			return nil, err
-		}
-		rec_list[i] = r
-	}
-	return rec_list, nil
+	return d.decodeRecordList(t.Records)
 }
 
-func DecodeRecordsWithTotalCount(b []byte) ([]*Record, string, error) {
+// DecodeRecordsWithTotalCount decodes JSON response for multi-get API
+// with totalCount.
+func (d *Decoder) DecodeRecordsWithTotalCount(b []byte) ([]*Record, string, error) {
 	var t struct {
 		Records    []recordData `json:"records"`
 		TotalCount string       `json:"totalCount"`
@@ -351,21 +553,15 @@ func DecodeRecordsWithTotalCount(b []byte) ([]*Record, string, error) {
 	if err != nil {
 		return nil, "", errors.New("Invalid JSON format")
 	}
-
-	rec_list := make([]*Record, len(t.Records))
-	for i, rd := range t.Records {
-		r, err := decodeRecordData(rd)
-		if err != nil {
-			return nil, "", err
-		}
-		rec_list[i] = r
+	recs, err := d.decodeRecordList(t.Records)
+	if recs == nil {
+		return nil, "", err
 	}
-
-	return rec_list, t.TotalCount, nil
+	return recs, t.TotalCount, err
 }
 
 // DecodeRecord decodes JSON response for single-get API.
-func DecodeRecord(b []byte) (*Record, error) {
+func (d *Decoder) DecodeRecord(b []byte) (*Record, error) {
 	var t struct {
 		RecordData recordData `json:"record"`
 	}
@@ -373,9 +569,25 @@ func DecodeRecord(b []byte) (*Record, error) {
 	if err != nil {
 		return nil, errors.New("Invalid JSON format")
 	}
-	rec, err := decodeRecordData(t.RecordData)
-	if err != nil {
+	rec, errs := d.decodeRecordData(t.RecordData)
+	rec.takeSnapshot()
+	ok, err := d.result(errs)
+	if !ok {
 		return nil, err
 	}
-	return rec, nil
+	return rec, err
+}
+
+// DecodeRecords decodes JSON response for multi-get API.
+func DecodeRecords(b []byte) ([]*Record, error) {
+	return (&Decoder{Logger: slog.Default()}).DecodeRecords(b)
+}
+
+func DecodeRecordsWithTotalCount(b []byte) ([]*Record, string, error) {
+	return (&Decoder{Logger: slog.Default()}).DecodeRecordsWithTotalCount(b)
+}
+
+// DecodeRecord decodes JSON response for single-get API.
+func DecodeRecord(b []byte) (*Record, error) {
+	return (&Decoder{Logger: slog.Default()}).DecodeRecord(b)
 }
@@ -5,6 +5,10 @@
 package kintone
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -462,3 +466,265 @@ func TestDecodeRecordsWithTotalCount(t *testing.T) {
 		t.Error("dropdown must be invalid")
 	}
 }
+
+const brokenRecordJSON = `{
+    "record": {
+        "$id": {"type": "__ID__", "value": "3"},
+        "title": {"type": "SINGLE_LINE_TEXT", "value": "hoge"},
+        "price": {"type": "NUMBER", "value": 123},
+        "creator": {"type": "CREATOR", "value": null},
+        "modifier": {"type": "MODIFIER", "value": "sato"},
+        "date": {"type": "DATE", "value": null},
+        "table": {"type": "SUBTABLE", "value": [
+            {"id": "10", "value": {"qty": {"type": "NUMBER", "value": ["1"]}}},
+            {"id": "11", "value": {"qty": {"type": "NUMBER", "value": "2"}}}
+        ]},
+        "broken": 1
+    }
+}`
+
+func TestDecodeRecordStrict(t *testing.T) {
+	rec, err := DecodeRecord([]byte(brokenRecordJSON))
+	if rec != nil {
+		t.Error("strict decoding must not return a record")
+	}
+	var fe *FieldDecodeError
+	if !errors.As(err, &fe) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fe.FieldCode != "broken" || fe.RecordIndex != -1 || fe.Row != -1 {
+		t.Errorf("unexpected field error: %+v", fe)
+	}
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Error("field errors must be ErrInvalidResponse")
+	}
+}
+
+func TestDecodeRecordLenient(t *testing.T) {
+	d := &Decoder{Lenient: true}
+	rec, err := d.DecodeRecord([]byte(brokenRecordJSON))
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		`kintone: cannot decode broken (): json: cannot unmarshal number into Go value of type kintone.fieldData`,
+		`kintone: cannot decode modifier (MODIFIER): json: cannot unmarshal string into Go value of type kintone.User`,
+		`kintone: cannot decode price (NUMBER): json: cannot unmarshal number into Go value of type string`,
+		`kintone: cannot decode table[0].qty (NUMBER): json: cannot unmarshal array into Go value of type string`,
+	}
+	if len(de.Errors) != len(expected) {
+		t.Fatalf("unexpected errors: %v", de.Errors)
+	}
+	for i, s := range expected {
+		if de.Errors[i].Error() != s {
+			t.Errorf("unexpected error: %v", de.Errors[i])
+		}
+	}
+
+	if rec == nil || rec.Id() != 3 {
+		t.Fatalf("partial record must be returned: %+v", rec)
+	}
+	if rec.Fields["title"] != SingleLineTextField("hoge") {
+		t.Errorf("unexpected title: %v", rec.Fields["title"])
+	}
+	if _, ok := rec.Fields["price"]; ok {
+		t.Error("broken field must be skipped")
+	}
+	if rec.Fields["creator"] != (CreatorField{}) || rec.Fields["date"] != (DateField{Valid: false}) {
+		t.Errorf("null values must be decoded: %v %v", rec.Fields["creator"], rec.Fields["date"])
+	}
+	table := rec.Fields["table"].(SubTableField)
+	if len(table) != 2 || len(table[0].Fields) != 0 || table[1].Fields["qty"] != DecimalField("2") {
+		t.Errorf("unexpected table: %+v", table)
+	}
+}
+
+func TestDecodeRecordsLenient(t *testing.T) {
+	d := &Decoder{Lenient: true}
+	recs, err := d.DecodeRecords([]byte(`{"records": [
+		{"title": {"type": "SINGLE_LINE_TEXT", "value": "a"}},
+		{"title": {"type": "SINGLE_LINE_TEXT", "value": {}}}
+	]}`))
+	var de *DecodeError
+	if !errors.As(err, &de) || len(de.Errors) != 1 || de.Errors[0].RecordIndex != 1 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recs) != 2 || recs[0].Fields["title"] != SingleLineTextField("a") {
+		t.Errorf("unexpected records: %v", recs)
+	}
+}
+
+func FuzzDecodeRecord(f *testing.F) {
+	f.Add([]byte(brokenRecordJSON))
+	f.Add([]byte(`{"record": {"a": {"type": "RECORD_NUMBER", "value": "APP-1"}}}`))
+	f.Add([]byte(`{"record": {"a": {"type": "SUBTABLE", "value": [{"id": "1", "value": null}]}}}`))
+	f.Add([]byte(`{"record": null}`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		rec, err := DecodeRecord(b)
+		if rec == nil && err == nil {
+			t.Error("either a record or an error must be returned")
+		}
+		rec, err = (&Decoder{Lenient: true}).DecodeRecord(b)
+		if rec == nil && err == nil {
+			t.Error("either a record or an error must be returned")
+		}
+	})
+}
+
+func FuzzDecodeRecords(f *testing.F) {
+	f.Add([]byte(`{"records": [{"a": {"type": "DATETIME", "value": ""}}, {"b": {"type": "TIME", "value": "10:04:37"}}]}`))
+	f.Add([]byte(`{"records": [{"a": {"type": "USER_SELECT", "value": [{"code": 1}]}}]}`))
+	f.Add([]byte(`{"records": [null, {"a": {"type": "__REVISION__", "value": "x"}}]}`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		recs, err := DecodeRecords(b)
+		if err == nil {
+			for _, rec := range recs {
+				if rec == nil {
+					t.Fatal("nil record")
+				}
+			}
+		}
+		(&Decoder{Lenient: true}).DecodeRecords(b)
+	})
+}
+
+func TestRecordJSON(t *testing.T) {
+	t.Parallel()
+
+	recs := []*Record{
+		NewRecordWithIdAndRevision(3, 7, map[string]interface{}{
+			"title":    SingleLineTextField("hoge"),
+			"dropdown": SingleSelectField{Valid: false},
+			"date":     NewDateField(2019, time.March, 11),
+			"time":     NewTimeField(9, 53),
+			"user":     UserField{{"sato", "Noboru Sato"}},
+			"file":     FileField{{"text/plain", "abc", "a.txt", 12}},
+			"created":  CreationTimeField(time.Date(2012, 2, 3, 8, 50, 0, 0, time.UTC)),
+			"raw":      RawField{"NEW_TYPE", json.RawMessage(`{"a":[1]}`)},
+			"table": SubTableField{
+				NewRecordWithId(10, map[string]interface{}{"qty": DecimalField("2")}),
+			},
+		}),
+		NewRecord(map[string]interface{}{"title": SingleLineTextField("new")}),
+	}
+	b, err := json.Marshal(recs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []*Record
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("unexpected records: %s", b)
+	}
+	if decoded[0].Id() != 3 || decoded[0].Revision() != 7 {
+		t.Errorf("unexpected id and revision: %d %d", decoded[0].Id(), decoded[0].Revision())
+	}
+	if decoded[1].Id() != 0 || decoded[1].Revision() != -1 {
+		t.Errorf("unexpected id and revision: %d %d", decoded[1].Id(), decoded[1].Revision())
+	}
+	if _, ok := decoded[0].Fields["$id"]; ok {
+		t.Error("$id must not be a field")
+	}
+	if table := decoded[0].Fields["table"].(SubTableField); table[0].Id() != 10 {
+		t.Errorf("unexpected row id: %d", table[0].Id())
+	}
+	b2, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(b2) {
+		t.Errorf("records must round-trip:\n%s\n%s", b, b2)
+	}
+
+	var rec Record
+	if err := json.Unmarshal([]byte(`{"n":{"type":"NUMBER","value":1}}`), &rec); err == nil {
+		t.Error("invalid field must fail")
+	}
+}
+
+func TestRecordRequestBody(t *testing.T) {
+	var body string
+	app := newApp()
+	app.Middlewares = []Middleware{func(next APIHandler) APIHandler {
+		return func(ctx context.Context, call *APICall) (*APIResult, error) {
+			b, _ := json.Marshal(call.Body)
+			body = string(b)
+			return &APIResult{StatusCode: 200, Body: []byte(`{}`)}, nil
+		}
+	}}
+	rec := NewRecordWithIdAndRevision(3, 7, map[string]interface{}{"title": SingleLineTextField("a")})
+	if err := app.UpdateRecord(rec, false); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"app":"1","id":"3","revision":"7","record":{"title":{"type":"SINGLE_LINE_TEXT","value":"a"}}}`
+	if body != expected {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestUpdateChangedFields(t *testing.T) {
+	var bodies []string
+	app := newApp()
+	app.Middlewares = []Middleware{func(next APIHandler) APIHandler {
+		return func(ctx context.Context, call *APICall) (*APIResult, error) {
+			b, _ := json.Marshal(call.Body)
+			bodies = append(bodies, string(b))
+			return &APIResult{StatusCode: 200, Body: []byte(`{}`)}, nil
+		}
+	}}
+
+	rec, err := DecodeRecord([]byte(`{"record": {
+		"$id": {"type": "__ID__", "value": "3"},
+		"$revision": {"type": "__REVISION__", "value": "7"},
+		"title": {"type": "SINGLE_LINE_TEXT", "value": "a"},
+		"note": {"type": "MULTI_LINE_TEXT", "value": "b"},
+		"total": {"type": "CALC", "value": "10"},
+		"creator": {"type": "CREATOR", "value": {"code": "sato", "name": "Noboru Sato"}},
+		"table": {"type": "SUBTABLE", "value": [{"id": "10", "value": {
+			"qty": {"type": "NUMBER", "value": "2"},
+			"sum": {"type": "CALC", "value": "4"}}}]}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if codes := rec.ChangedFields(); len(codes) != 0 {
+		t.Errorf("decoded records must be unchanged: %v", codes)
+	}
+
+	rec.Fields["title"] = SingleLineTextField("x")
+	rows, _ := rec.SubTable("table")
+	rows[0].Fields["qty"] = DecimalField("3")
+	if codes := rec.ChangedFields(); !reflect.DeepEqual(codes, []string{"table", "title"}) {
+		t.Errorf("unexpected changed fields: %v", codes)
+	}
+	if err := app.UpdateRecord(rec, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.UpdateRecords([]*Record{rec}, false); err != nil {
+		t.Fatal(err)
+	}
+	app.SendAllFields = true
+	if err := app.UpdateRecord(rec, true); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`{"app":"1","id":"3","revision":"7","record":{` +
+			`"table":{"type":"SUBTABLE","value":[{"id":"10","value":{"qty":{"type":"NUMBER","value":"3"}}}]},` +
+			`"title":{"type":"SINGLE_LINE_TEXT","value":"x"}}}`,
+		`{"app":"1","records":[{"id":"3","revision":"7","record":{}}]}`,
+		`{"app":"1","id":"3","revision":"-1","record":{` +
+			`"creator":{"type":"CREATOR","value":{"code":"sato","name":"Noboru Sato"}},` +
+			`"note":{"type":"MULTI_LINE_TEXT","value":"b"},` +
+			`"table":{"type":"SUBTABLE","value":[{"id":"10","value":{"qty":{"type":"NUMBER","value":"3"},"sum":{"type":"CALC","value":"4"}}}]},` +
+			`"title":{"type":"SINGLE_LINE_TEXT","value":"x"},"total":{"type":"CALC","value":"10"}}}`,
+	}
+	if !reflect.DeepEqual(bodies, expected) {
+		for _, b := range bodies {
+			t.Errorf("unexpected body: %s", b)
+		}
+	}
+}
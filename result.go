@@ -0,0 +1,229 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+// RecordResult is the ID and the revision of a record after a write.
+type RecordResult struct {
+	Id       uint64 // ID of the record.
+	Revision int64  // Revision of the record after the write.
+}
+
+// recordResult is a record result in API responses.
+type recordResult struct {
+	Id       string `json:"id"`
+	Revision string `json:"revision"`
+}
+
+// result parses r.  If r has no ID, id is used instead.
+func (r recordResult) result(id uint64) (RecordResult, error) {
+	if len(r.Id) > 0 {
+		var err error
+		if id, err = strconv.ParseUint(r.Id, 10, 64); err != nil {
+			return RecordResult{}, ErrInvalidResponse
+		}
+	}
+	revision, err := strconv.ParseInt(r.Revision, 10, 64)
+	if err != nil {
+		return RecordResult{}, ErrInvalidResponse
+	}
+	return RecordResult{id, revision}, nil
+}
+
+// syncRecord stores the ID and the revision of r in rec.
+func syncRecord(rec *Record, r RecordResult) {
+	if r.Id != 0 {
+		rec.id = r.Id
+	}
+	rec.revision = r.Revision
+}
+
+// AddRecordWithResult is like AddRecord but returns the ID and the
+// revision of the added record.  They are also stored in rec if
+// app.SyncRecords is set.
+func (app *App) AddRecordWithResult(rec *Record) (*RecordResult, error) {
+	return app.AddRecordWithResultContext(context.Background(), rec)
+}
+
+// AddRecordWithResultContext is like AddRecordWithResult but uses ctx for the API request.
+func (app *App) AddRecordWithResultContext(ctx context.Context, rec *Record) (*RecordResult, error) {
+	body, err := app.addRecord(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	return app.addedResult(body, rec)
+}
+
+// AddRecordsWithResult is like AddRecords but returns the IDs and the
+// revisions of the added records in the order of recs.  See
+// AddRecordWithResult.
+func (app *App) AddRecordsWithResult(recs []*Record) ([]RecordResult, error) {
+	return app.AddRecordsWithResultContext(context.Background(), recs)
+}
+
+// AddRecordsWithResultContext is like AddRecordsWithResult but uses ctx for the API request.
+func (app *App) AddRecordsWithResultContext(ctx context.Context, recs []*Record) ([]RecordResult, error) {
+	body, err := app.addRecords(ctx, recs)
+	if err != nil {
+		return nil, err
+	}
+	return app.addedResults(body, recs)
+}
+
+// UpdateRecordWithResult is like UpdateRecord but returns the ID and the
+// new revision of the record.  See AddRecordWithResult.
+func (app *App) UpdateRecordWithResult(rec *Record, ignoreRevision bool) (*RecordResult, error) {
+	return app.UpdateRecordWithResultContext(context.Background(), rec, ignoreRevision)
+}
+
+// UpdateRecordWithResultContext is like UpdateRecordWithResult but uses ctx for the API request.
+func (app *App) UpdateRecordWithResultContext(ctx context.Context, rec *Record, ignoreRevision bool) (*RecordResult, error) {
+	body, err := app.updateRecord(ctx, rec, ignoreRevision)
+	if err != nil {
+		return nil, err
+	}
+	return app.revisionResult(body, rec)
+}
+
+// UpdateRecordByKeyWithResult is like UpdateRecordByKey but returns the
+// new revision of the record.  The ID in the result is that of rec,
+// which is 0 unless rec was fetched or synced.  See AddRecordWithResult.
+func (app *App) UpdateRecordByKeyWithResult(rec *Record, ignoreRevision bool, keyField string) (*RecordResult, error) {
+	return app.UpdateRecordByKeyWithResultContext(context.Background(), rec, ignoreRevision, keyField)
+}
+
+// UpdateRecordByKeyWithResultContext is like UpdateRecordByKeyWithResult but uses ctx for the API request.
+func (app *App) UpdateRecordByKeyWithResultContext(ctx context.Context, rec *Record, ignoreRevision bool, keyField string) (*RecordResult, error) {
+	body, err := app.updateRecordByKey(ctx, rec, ignoreRevision, keyField)
+	if err != nil {
+		return nil, err
+	}
+	return app.revisionResult(body, rec)
+}
+
+// UpdateRecordsWithResult is like UpdateRecords but returns the IDs and
+// the new revisions of the records in the order of recs.  See
+// AddRecordWithResult.
+func (app *App) UpdateRecordsWithResult(recs []*Record, ignoreRevision bool) ([]RecordResult, error) {
+	return app.UpdateRecordsWithResultContext(context.Background(), recs, ignoreRevision)
+}
+
+// UpdateRecordsWithResultContext is like UpdateRecordsWithResult but uses ctx for the API request.
+func (app *App) UpdateRecordsWithResultContext(ctx context.Context, recs []*Record, ignoreRevision bool) ([]RecordResult, error) {
+	body, err := app.updateRecords(ctx, recs, ignoreRevision)
+	if err != nil {
+		return nil, err
+	}
+	return app.updatedResults(body, recs)
+}
+
+// UpdateRecordsByKeyWithResult is like UpdateRecordsByKey but returns
+// the IDs and the new revisions of the records in the order of recs.
+// See AddRecordWithResult.
+func (app *App) UpdateRecordsByKeyWithResult(recs []*Record, ignoreRevision bool, keyField string) ([]RecordResult, error) {
+	return app.UpdateRecordsByKeyWithResultContext(context.Background(), recs, ignoreRevision, keyField)
+}
+
+// UpdateRecordsByKeyWithResultContext is like UpdateRecordsByKeyWithResult but uses ctx for the API request.
+func (app *App) UpdateRecordsByKeyWithResultContext(ctx context.Context, recs []*Record, ignoreRevision bool, keyField string) ([]RecordResult, error) {
+	body, err := app.updateRecordsByKey(ctx, recs, ignoreRevision, keyField)
+	if err != nil {
+		return nil, err
+	}
+	return app.updatedResults(body, recs)
+}
+
+// UpdateRecordStatusWithResult is like UpdateRecordStatus but returns
+// the ID and the new revision of the record.  See AddRecordWithResult.
+func (app *App) UpdateRecordStatusWithResult(rec *Record, action *ProcessAction, assignee *Entity, ignoreRevision bool) (*RecordResult, error) {
+	return app.UpdateRecordStatusWithResultContext(context.Background(), rec, action, assignee, ignoreRevision)
+}
+
+// UpdateRecordStatusWithResultContext is like UpdateRecordStatusWithResult but uses ctx for the API request.
+func (app *App) UpdateRecordStatusWithResultContext(ctx context.Context, rec *Record, action *ProcessAction, assignee *Entity, ignoreRevision bool) (*RecordResult, error) {
+	body, err := app.call(ctx, "PUT", "record/status", updateRecordStatusRequest(app.AppId, rec, action, assignee, ignoreRevision))
+	if err != nil {
+		return nil, err
+	}
+	return app.revisionResult(body, rec)
+}
+
+// addedResult parses the response of adding rec.
+func (app *App) addedResult(body []byte, rec *Record) (*RecordResult, error) {
+	var t recordResult
+	if json.Unmarshal(body, &t) != nil || len(t.Id) == 0 {
+		return nil, ErrInvalidResponse
+	}
+	return app.syncResult(t, rec)
+}
+
+// revisionResult parses the response of updating rec, which has the
+// revision only.
+func (app *App) revisionResult(body []byte, rec *Record) (*RecordResult, error) {
+	var t recordResult
+	if json.Unmarshal(body, &t) != nil {
+		return nil, ErrInvalidResponse
+	}
+	return app.syncResult(t, rec)
+}
+
+func (app *App) syncResult(t recordResult, rec *Record) (*RecordResult, error) {
+	result, err := t.result(rec.id)
+	if err != nil {
+		return nil, err
+	}
+	if app.SyncRecords {
+		syncRecord(rec, result)
+	}
+	return &result, nil
+}
+
+// addedResults parses the response of adding recs.
+func (app *App) addedResults(body []byte, recs []*Record) ([]RecordResult, error) {
+	var t struct {
+		Ids       []string `json:"ids"`
+		Revisions []string `json:"revisions"`
+	}
+	if json.Unmarshal(body, &t) != nil || len(t.Ids) != len(recs) || len(t.Revisions) != len(recs) {
+		return nil, ErrInvalidResponse
+	}
+	rs := make([]recordResult, len(recs))
+	for i := range rs {
+		rs[i] = recordResult{t.Ids[i], t.Revisions[i]}
+	}
+	return app.syncResults(rs, recs)
+}
+
+// updatedResults parses the response of updating recs.
+func (app *App) updatedResults(body []byte, recs []*Record) ([]RecordResult, error) {
+	var t struct {
+		Records []recordResult `json:"records"`
+	}
+	if json.Unmarshal(body, &t) != nil || len(t.Records) != len(recs) {
+		return nil, ErrInvalidResponse
+	}
+	return app.syncResults(t.Records, recs)
+}
+
+func (app *App) syncResults(rs []recordResult, recs []*Record) ([]RecordResult, error) {
+	results := make([]RecordResult, len(rs))
+	for i, r := range rs {
+		var err error
+		if results[i], err = r.result(recs[i].id); err != nil {
+			return nil, err
+		}
+	}
+	if app.SyncRecords {
+		for i, rec := range recs {
+			syncRecord(rec, results[i])
+		}
+	}
+	return results, nil
+}
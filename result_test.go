@@ -0,0 +1,92 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// newResultTestApp returns an app whose write APIs respond with the
+// results of kintone.
+func newResultTestApp(t *testing.T) *App {
+	return newTestApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data *TestData
+		switch r.Method + " " + r.URL.Path {
+		case "POST /k/v1/record.json":
+			data = GetTestDataAddRecord()
+		case "POST /k/v1/records.json":
+			data = GetTestDataAddRecords()
+		case "PUT /k/v1/record.json", "PUT /k/v1/record/status.json":
+			data = GetTestDataUpdateRecord()
+		case "PUT /k/v1/records.json":
+			data = GetTestDataUpdateRecords()
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			return
+		}
+		respond(http.StatusOK, data.output)(w, r)
+	}))
+}
+
+func TestWriteWithResult(t *testing.T) {
+	app := newResultTestApp(t)
+	rec := NewRecord(map[string]interface{}{})
+	result, err := app.AddRecordWithResult(rec)
+	if err != nil || *result != (RecordResult{1, 1}) {
+		t.Fatalf("unexpected result: %v %v", result, err)
+	}
+	if rec.Id() != 0 || rec.Revision() != -1 {
+		t.Error("records must not be synced by default")
+	}
+
+	app.SyncRecords = true
+	if _, err := app.AddRecordWithResult(rec); err != nil || rec.Id() != 1 || rec.Revision() != 1 {
+		t.Errorf("unexpected record: %d %d %v", rec.Id(), rec.Revision(), err)
+	}
+	if result, err := app.UpdateRecordWithResult(rec, false); err != nil || *result != (RecordResult{1, 6}) || rec.Revision() != 6 {
+		t.Errorf("unexpected result: %v %v", result, err)
+	}
+	key := NewRecord(map[string]interface{}{"key": SingleLineTextField("a")})
+	if result, err := app.UpdateRecordByKeyWithResult(key, true, "key"); err != nil || *result != (RecordResult{0, 6}) {
+		t.Errorf("unexpected result: %v %v", result, err)
+	}
+	if result, err := app.UpdateRecordStatusWithResult(rec, &ProcessAction{Name: "Start"}, nil, false); err != nil || *result != (RecordResult{1, 6}) {
+		t.Errorf("unexpected result: %v %v", result, err)
+	}
+
+	recs := []*Record{NewRecord(map[string]interface{}{}), NewRecord(map[string]interface{}{})}
+	results, err := app.AddRecordsWithResult(recs)
+	if err != nil || !reflect.DeepEqual(results, []RecordResult{{77, 1}, {78, 1}}) {
+		t.Errorf("unexpected results: %v %v", results, err)
+	}
+	if recs[1].Id() != 78 || recs[1].Revision() != 1 {
+		t.Errorf("unexpected record: %d %d", recs[1].Id(), recs[1].Revision())
+	}
+	results, err = app.UpdateRecordsWithResult(recs, false)
+	if err != nil || !reflect.DeepEqual(results, []RecordResult{{3, 6}, {4, 2}}) {
+		t.Errorf("unexpected results: %v %v", results, err)
+	}
+	if _, err := app.UpdateRecordsByKeyWithResult([]*Record{key}, false, "key"); err != ErrInvalidResponse {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSyncRecords(t *testing.T) {
+	app := newResultTestApp(t)
+	app.SyncRecords = true
+	rec := NewRecord(map[string]interface{}{})
+	if id, err := app.AddRecord(rec); err != nil || id != "1" || rec.Id() != 1 || rec.Revision() != 1 {
+		t.Errorf("unexpected result: %s %v", id, err)
+	}
+	if err := app.UpdateRecord(rec, false); err != nil || rec.Revision() != 6 {
+		t.Errorf("unexpected revision: %d %v", rec.Revision(), err)
+	}
+	recs := []*Record{NewRecordWithIdAndRevision(3, 5, nil), NewRecordWithIdAndRevision(4, 1, nil)}
+	if err := app.UpdateRecords(recs, false); err != nil || recs[0].Revision() != 6 || recs[1].Revision() != 2 {
+		t.Errorf("unexpected revisions: %d %d %v", recs[0].Revision(), recs[1].Revision(), err)
+	}
+}
@@ -0,0 +1,163 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Default values for RetryPolicy.
+const (
+	DEFAULT_RETRY_MIN_BACKOFF = time.Millisecond * 500
+	DEFAULT_RETRY_MAX_BACKOFF = time.Second * 30
+)
+
+// DefaultRetryCodes is the list of kintone error codes which mean the
+// request was rejected before being processed, and hence can be sent
+// again safely.
+var DefaultRetryCodes = []string{
+	"GAIA_TM12", // Too many concurrent requests.
+}
+
+// RetryPolicy controls how App retries requests that failed transiently.
+//
+// Network errors, timeouts, HTTP 429 and HTTP 5xx responses are transient
+// failures.  Requests are retried with an exponential backoff with jitter.
+// If the server sends a Retry-After header, the delay is as long as
+// requested, up to MaxBackoff.
+//
+// GET requests are retried on any transient failure.  Other requests such
+// as AddRecords may have been processed by kintone even if they failed,
+// so they are retried only when kintone surely rejected them: HTTP 429,
+// HTTP 503, or one of Codes.  Set RetryWrites to retry them on any
+// transient failure.
+//
+// Requests whose body cannot be sent again, such as Upload, are never
+// retried.
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first one.  <= 1 disables retries.
+	MinBackoff  time.Duration // Delay before the first retry.  Default is DEFAULT_RETRY_MIN_BACKOFF.
+	MaxBackoff  time.Duration // Upper limit of the delay.  Default is DEFAULT_RETRY_MAX_BACKOFF.
+	RetryWrites bool          // true to retry writes on any transient failure.
+	Codes       []string      // Retryable kintone error codes.  nil means DefaultRetryCodes.
+}
+
+// NewRetryPolicy returns a RetryPolicy which tries up to maxAttempts times
+// with the default backoff.
+func NewRetryPolicy(maxAttempts int) *RetryPolicy {
+	return &RetryPolicy{MaxAttempts: maxAttempts}
+}
+
+func (p *RetryPolicy) codes() []string {
+	if p.Codes == nil {
+		return DefaultRetryCodes
+	}
+	return p.Codes
+}
+
+func (p *RetryPolicy) isRetryableCode(code string) bool {
+	for _, c := range p.codes() {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff <= 0 {
+		return DEFAULT_RETRY_MAX_BACKOFF
+	}
+	return p.MaxBackoff
+}
+
+// backoff returns the delay before the attempt-th retry.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	min, max := p.MinBackoff, p.maxBackoff()
+	if min <= 0 {
+		min = DEFAULT_RETRY_MIN_BACKOFF
+	}
+	d := min
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	// Equal jitter: half fixed, half random.
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// retryAfter parses the Retry-After header of resp.
+func retryAfter(resp *http.Response) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if len(v) == 0 {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// peekErrorCode returns the kintone error code in resp without consuming
+// its body.
+func peekErrorCode(resp *http.Response) string {
+	if !isJSON(resp.Header.Get("Content-Type")) {
+		return ""
+	}
+	var t struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(peekBody(resp), &t)
+	return t.Code
+}
+
+// check decides whether the attempt-th try of req should be retried.
+// If so, it returns the delay before the next try.
+func (p *RetryPolicy) check(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if p == nil || attempt >= p.MaxAttempts || req.Context().Err() != nil {
+		return 0, false
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return 0, false
+	}
+
+	idempotent := req.Method == "GET" || req.Method == "HEAD"
+	if err == ErrQuotaExceeded {
+		return 0, false
+	}
+	if err != nil {
+		if idempotent || p.RetryWrites {
+			return p.backoff(attempt), true
+		}
+		return 0, false
+	}
+	if resp.StatusCode < 400 {
+		return 0, false
+	}
+
+	rejected := resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusServiceUnavailable ||
+		p.isRetryableCode(peekErrorCode(resp))
+	transient := rejected || resp.StatusCode >= 500
+	if !transient || !(rejected || idempotent || p.RetryWrites) {
+		return 0, false
+	}
+
+	d := p.backoff(attempt)
+	if ra := retryAfter(resp); ra > d {
+		d = min(ra, p.maxBackoff())
+	}
+	return d, true
+}
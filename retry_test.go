@@ -0,0 +1,115 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRetryTestApp returns an App connected to a server which fails
+// the first `failures` requests with the given status and body.
+func newRetryTestApp(t *testing.T, failures int32, status int, body string) (*App, *int32) {
+	var count int32
+	app := newTestApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) <= failures {
+			respond(status, body)(w, r)
+			return
+		}
+		respond(http.StatusOK, `{"ids":["1"],"revisions":["1"],"records":[]}`)(w, r)
+	}))
+	app.Retry = &RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond * 5,
+	}
+	return app, &count
+}
+
+func TestRetryRead(t *testing.T) {
+	app, count := newRetryTestApp(t, 2, http.StatusBadGateway, `{}`)
+	if _, err := app.GetRecords(nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if *count != 3 {
+		t.Errorf("expected 3 attempts, got %d", *count)
+	}
+}
+
+func TestRetryGiveUp(t *testing.T) {
+	app, count := newRetryTestApp(t, 5, http.StatusInternalServerError, `{"code":"GAIA_XX01"}`)
+	if _, err := app.GetRecords(nil, ""); err == nil {
+		t.Fatal("GetRecords must fail")
+	}
+	if *count != 3 {
+		t.Errorf("expected 3 attempts, got %d", *count)
+	}
+}
+
+func TestRetryWrite(t *testing.T) {
+	recs := []*Record{NewRecord(map[string]interface{}{
+		"title": SingleLineTextField("retry"),
+	})}
+
+	// A write which may have been processed is not retried.
+	app, count := newRetryTestApp(t, 1, http.StatusInternalServerError, `{}`)
+	if _, err := app.AddRecords(recs); err == nil {
+		t.Error("AddRecords must fail")
+	}
+	if *count != 1 {
+		t.Errorf("expected 1 attempt, got %d", *count)
+	}
+
+	// ...unless the caller opts in.
+	app, count = newRetryTestApp(t, 1, http.StatusInternalServerError, `{}`)
+	app.Retry.RetryWrites = true
+	if _, err := app.AddRecords(recs); err != nil {
+		t.Error(err)
+	}
+	if *count != 2 {
+		t.Errorf("expected 2 attempts, got %d", *count)
+	}
+
+	// Throttled writes are always retried.
+	app, count = newRetryTestApp(t, 1, http.StatusTooManyRequests, `{}`)
+	if _, err := app.AddRecords(recs); err != nil {
+		t.Error(err)
+	}
+	if *count != 2 {
+		t.Errorf("expected 2 attempts, got %d", *count)
+	}
+	app, count = newRetryTestApp(t, 1, http.StatusBadRequest, `{"code":"GAIA_TM12"}`)
+	if _, err := app.AddRecords(recs); err != nil {
+		t.Error(err)
+	}
+	if *count != 2 {
+		t.Errorf("expected 2 attempts, got %d", *count)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Retry-After": {"3"}}}
+	if d := retryAfter(resp); d != time.Second*3 {
+		t.Errorf("unexpected Retry-After: %v", d)
+	}
+	p := &RetryPolicy{MaxAttempts: 3, MinBackoff: time.Second, MaxBackoff: time.Second * 4}
+	for attempt := 1; attempt < 6; attempt++ {
+		if d := p.backoff(attempt); d < time.Millisecond*500 || d > time.Second*4 {
+			t.Errorf("backoff out of range: %v", d)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "https://example.cybozu.com/k/v1/records.json", nil)
+	resp = &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"3"}}}
+	if d, ok := p.check(req, resp, nil, 1); !ok || d != time.Second*3 {
+		t.Errorf("unexpected delay: %v %v", d, ok)
+	}
+	resp.Header.Set("Retry-After", "86400")
+	if d, ok := p.check(req, resp, nil, 1); !ok || d != time.Second*4 {
+		t.Errorf("Retry-After must be clamped to MaxBackoff: %v %v", d, ok)
+	}
+}
@@ -0,0 +1,327 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// scanLimit is the maximum number of records fetched at once.
+const scanLimit = 500
+
+// ScanProgress reports the progress of ScanRecords.
+type ScanProgress struct {
+	Fetched int    // Number of records fetched so far.
+	Total   int    // Number of records matching the query when the scan started.
+	LastId  uint64 // The largest record ID fetched so far.
+}
+
+// ScanRecords fetches every record matching query in ascending order
+// of the record ID.
+//
+// Records are fetched by up to 500 records with a query that seeks by
+// the record ID, "$id > (last ID) order by $id asc", so that the scan
+// is not limited by the maximum offset of kintone and does not skip or
+// repeat records updated during the scan.  query must consist of
+// conditions only, without "order by", "limit" or "offset".  An empty
+// query matches every record.
+//
+// If fields is not nil, only the fields are retrieved.  "$id" is added
+// to fields as it is required to seek.
+//
+// fn is called with each page of records and the progress.  An error
+// returned by fn stops the scan and is returned by ScanRecords.
+func (app *App) ScanRecords(fields []string, query string, fn func(recs []*Record, progress ScanProgress) error) error {
+	return app.ScanRecordsContext(context.Background(), fields, query, fn)
+}
+
+// ScanRecordsContext is like ScanRecords but uses ctx for the API requests.
+func (app *App) ScanRecordsContext(ctx context.Context, fields []string, query string, fn func(recs []*Record, progress ScanProgress) error) error {
+	if fields != nil {
+		fields = withIdField(fields)
+	}
+	var derr *DecodeError
+	progress := ScanProgress{Total: -1}
+	for {
+		cond := fmt.Sprintf("$id > %d", progress.LastId)
+		if len(query) > 0 {
+			cond += " and (" + query + ")"
+		}
+		q := fmt.Sprintf("%s order by $id asc limit %d", cond, scanLimit)
+		recs, totalCount, err := app.getRecords(ctx, fields, q, progress.Total < 0)
+		if e, ok := err.(*DecodeError); ok && recs != nil {
+			if derr == nil {
+				derr = &DecodeError{}
+			}
+			for _, fe := range e.Errors {
+				fe.RecordIndex += progress.Fetched
+			}
+			derr.Errors = append(derr.Errors, e.Errors...)
+		} else if err != nil {
+			return err
+		}
+		if progress.Total < 0 {
+			if progress.Total, err = strconv.Atoi(totalCount); err != nil {
+				progress.Total = 0
+			}
+		}
+		if len(recs) == 0 {
+			break
+		}
+		for _, rec := range recs {
+			if rec.Id() <= progress.LastId {
+				return ErrInvalidResponse
+			}
+			progress.LastId = rec.Id()
+		}
+		progress.Fetched += len(recs)
+		if err := fn(recs, progress); err != nil {
+			return err
+		}
+		if len(recs) < scanLimit {
+			break
+		}
+	}
+	if derr != nil {
+		return derr
+	}
+	return nil
+}
+
+// withIdField returns fields including "$id".
+func withIdField(fields []string) []string {
+	for _, f := range fields {
+		if f == "$id" {
+			return fields
+		}
+	}
+	return append(append(make([]string, 0, len(fields)+1), fields...), "$id")
+}
+
+// MAX_PARALLEL_CURSORS is the maximum of ParallelScanOptions.Workers, which
+// is the number of cursors kintone allows to be open at once per domain.
+// It bounds the cursors of a call only; cursors opened elsewhere for the
+// same domain count toward the limit of kintone too.
+const MAX_PARALLEL_CURSORS = 10
+
+// ParallelScanOptions configures ParallelScanRecords.
+type ParallelScanOptions struct {
+	Workers    int    // Number of ranges read at once.  Default 4, up to MAX_PARALLEL_CURSORS.
+	Partitions int    // Number of $id ranges.  Default Workers * 4.
+	Ordered    bool   // true to pass records to fn in ascending order of the record ID.
+	PageSize   uint64 // Number of records passed to fn at once, up to 500.  Default 500.
+}
+
+// ParallelScanRecords fetches every record matching query like
+// ScanRecords, but splits the app into ranges of the record ID and reads
+// them through cursors in parallel.
+//
+// At most opts.Workers cursors are open at once; App.Limiter, if set,
+// bounds the number of requests in flight as usual.  query must consist
+// of conditions only.  opts may be nil for the defaults.  With
+// LenientDecoding, *DecodeError is returned after all of the records
+// are passed to fn; RecordIndex of the errors counts records in their
+// range.
+//
+// fn is called from one goroutine at a time with pages of records.
+// Records in a page are in ascending order of the record ID; pages
+// of different ranges are passed in arbitrary order unless opts.Ordered
+// is set.  An error returned by fn stops the scan and is returned.
+//
+// With opts.Ordered, a range is read only after the range opts.Workers
+// before it has been passed to fn, so that a slow fn keeps at most that
+// many cursors waiting.  kintone deletes cursors left unread for ten
+// minutes.
+func (app *App) ParallelScanRecords(fields []string, query string, opts *ParallelScanOptions, fn func(recs []*Record) error) error {
+	return app.ParallelScanRecordsContext(context.Background(), fields, query, opts, fn)
+}
+
+// ParallelScanRecordsContext is like ParallelScanRecords but uses ctx for the API requests.
+func (app *App) ParallelScanRecordsContext(ctx context.Context, fields []string, query string, opts *ParallelScanOptions, fn func(recs []*Record) error) error {
+	var o ParallelScanOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Workers <= 0 {
+		o.Workers = 4
+	}
+	if o.Workers > MAX_PARALLEL_CURSORS {
+		o.Workers = MAX_PARALLEL_CURSORS
+	}
+	if o.Partitions <= 0 {
+		o.Partitions = o.Workers * 4
+	}
+	if o.PageSize == 0 || o.PageSize > scanLimit {
+		o.PageSize = scanLimit
+	}
+
+	cond := ""
+	if len(query) > 0 {
+		cond = "(" + query + ") "
+	}
+	first, _, err := app.getRecords(ctx, []string{"$id"}, cond+"order by $id asc limit 1", false)
+	if err != nil {
+		return err
+	}
+	last, _, err := app.getRecords(ctx, []string{"$id"}, cond+"order by $id desc limit 1", false)
+	if err != nil {
+		return err
+	}
+	if len(first) == 0 || len(last) == 0 {
+		return nil
+	}
+	lo, hi := first[0].Id(), last[0].Id()
+	span := hi - lo + 1
+	n := uint64(o.Partitions)
+	if n > span {
+		n = span
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var errOnce sync.Once
+	var firstErr error
+	var mu sync.Mutex
+	var derr *DecodeError // Merged errors of the ranges partially decoded.
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	// Each range sends pages to its own channel if ordered, or to the
+	// shared one.
+	pages := make([]chan []*Record, n)
+	shared := make(chan []*Record, o.Workers)
+	for i := range pages {
+		if o.Ordered {
+			pages[i] = make(chan []*Record, 1)
+		} else {
+			pages[i] = shared
+		}
+	}
+	// Ordered ranges are queued as the preceding ones are delivered.
+	jobs := make(chan uint64, n)
+	queued := n
+	if o.Ordered && queued > uint64(o.Workers) {
+		queued = uint64(o.Workers)
+	}
+	for i := uint64(0); i < queued; i++ {
+		jobs <- i
+	}
+	if queued == n {
+		close(jobs)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < o.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				after, until := lo-1+span*i/n, lo-1+span*(i+1)/n
+				q := fmt.Sprintf("$id > %d and $id <= %d", after, until)
+				if len(query) > 0 {
+					q += " and (" + query + ")"
+				}
+				err := app.scanRange(ctx, fields, q+" order by $id asc", o.PageSize, pages[i])
+				if o.Ordered {
+					close(pages[i])
+				}
+				if e, ok := err.(*DecodeError); ok {
+					mu.Lock()
+					if derr == nil {
+						derr = &DecodeError{}
+					}
+					derr.Errors = append(derr.Errors, e.Errors...)
+					mu.Unlock()
+				} else if err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+	if !o.Ordered {
+		go func() {
+			wg.Wait()
+			close(shared)
+		}()
+	}
+
+	deliver := func(ch chan []*Record) {
+		for recs := range ch {
+			if ctx.Err() == nil {
+				if err := fn(recs); err != nil {
+					fail(err)
+				}
+			}
+		}
+	}
+	if o.Ordered {
+		for _, ch := range pages {
+			deliver(ch)
+			if queued < n {
+				jobs <- queued
+				if queued++; queued == n {
+					close(jobs)
+				}
+			}
+		}
+	} else {
+		deliver(shared)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if derr != nil {
+		return derr
+	}
+	return nil
+}
+
+// scanRange reads records through a cursor and sends them to out by
+// pages of size records.
+func (app *App) scanRange(ctx context.Context, fields []string, query string, size uint64, out chan<- []*Record) error {
+	it, err := app.IterateRecordsContext(ctx, fields, query, size)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	send := func(page []*Record) error {
+		select {
+		case out <- page:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	page := make([]*Record, 0, size)
+	for it.Next() {
+		page = append(page, it.Record())
+		if uint64(len(page)) == size {
+			if err := send(page); err != nil {
+				return err
+			}
+			page = make([]*Record, 0, size)
+		}
+	}
+	if _, ok := it.Err().(*DecodeError); it.Err() != nil && !ok {
+		return it.Err()
+	}
+	if len(page) > 0 {
+		if err := send(page); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
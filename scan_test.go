@@ -0,0 +1,292 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var scanQuery = regexp.MustCompile(`^\$id > (\d+)(?: and \$id <= (\d+))?(?: and \((.*)\))? order by \$id asc limit (\d+)$`)
+
+// newScanTestApp returns an app which has records whose IDs are
+// 1 to n except for multiples of 7.  Requests are recorded in queries.
+func newScanTestApp(t *testing.T, n int) (*App, *requestLog) {
+	queries := &requestLog{}
+	app := newTestApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Fields     []string `json:"fields"`
+			Query      string   `json:"query"`
+			TotalCount bool     `json:"totalCount"`
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+		queries.add(fmt.Sprintf("%s %v %v", body.Query, body.Fields, body.TotalCount))
+
+		m := scanQuery.FindStringSubmatch(body.Query)
+		if m == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":"GAIA_IQ11","id":"x","message":"Invalid query."}`)
+			return
+		}
+		after, _ := strconv.Atoi(m[1])
+		until, _ := strconv.Atoi(m[2])
+		limit, _ := strconv.Atoi(m[4])
+		var recs []string
+		total := 0
+		for id := 1; id <= n; id++ {
+			if id%7 == 0 || (m[3] == "odd" && id%2 == 0) || (until > 0 && id > until) {
+				continue
+			}
+			total++
+			if id > after && len(recs) < limit {
+				recs = append(recs, fmt.Sprintf(`{"$id":{"type":"__ID__","value":"%d"}}`, id))
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"records":[%s],"totalCount":"%d"}`, strings.Join(recs, ","), total)
+	}))
+	return app, queries
+}
+
+func TestScanRecords(t *testing.T) {
+	app, queries := newScanTestApp(t, 1200)
+	var progress []ScanProgress
+	var last uint64
+	err := app.ScanRecords([]string{"title"}, "odd", func(recs []*Record, p ScanProgress) error {
+		for _, rec := range recs {
+			if rec.Id() <= last {
+				t.Fatalf("records must be in ascending order: %d", rec.Id())
+			}
+			last = rec.Id()
+		}
+		progress = append(progress, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ScanProgress{{500, 514, 1165}, {514, 514, 1199}}
+	if fmt.Sprint(progress) != fmt.Sprint(expected) {
+		t.Errorf("unexpected progress: %v", progress)
+	}
+	expectedQueries := []string{
+		"$id > 0 and (odd) order by $id asc limit 500 [title $id] true",
+		"$id > 1165 and (odd) order by $id asc limit 500 [title $id] false",
+	}
+	if fmt.Sprint(queries.all()) != fmt.Sprint(expectedQueries) {
+		t.Errorf("unexpected queries: %q", queries.all())
+	}
+
+	errStop := errors.New("stop")
+	err = app.ScanRecords(nil, "", func(recs []*Record, p ScanProgress) error {
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGetAllRecordsSeek(t *testing.T) {
+	app, queries := newScanTestApp(t, 12000)
+	recs, err := app.GetAllRecords(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 12000-12000/7 || recs[len(recs)-1].Id() != 12000 {
+		t.Errorf("unexpected records: %d", len(recs))
+	}
+	for _, q := range queries.all() {
+		if strings.Contains(q, "offset") {
+			t.Errorf("offset must not be used: %s", q)
+		}
+	}
+}
+
+var (
+	boundQuery  = regexp.MustCompile(`^(?:\((.*)\) )?order by \$id (asc|desc) limit 1$`)
+	cursorQuery = regexp.MustCompile(`^\$id > (\d+) and \$id <= (\d+)(?: and \((.*)\))? order by \$id asc$`)
+)
+
+// newParallelScanTestApp returns an app like newScanTestApp which also
+// serves cursors.  The largest number of open cursors is kept in peak,
+// and the number of cursors created so far in created.  Records of
+// cursors for "broken" whose IDs are multiples of 1000 cannot be decoded.
+func newParallelScanTestApp(t *testing.T, n int) (*App, *int, *int32) {
+	var mu sync.Mutex
+	type cursor struct {
+		ids    []int
+		size   int
+		broken bool
+	}
+	cursors := map[string]*cursor{}
+	seq, peak := 0, 0
+	var created int32
+	match := func(id int, cond string) bool {
+		return id%7 != 0 && (cond != "odd" || id%2 == 1)
+	}
+	app := newTestApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Id    string `json:"id"`
+			Query string `json:"query"`
+			Size  int    `json:"size"`
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		record := func(id int, broken bool) string {
+			if broken && id%1000 == 0 {
+				return fmt.Sprintf(`{"$id":{"type":"__ID__","value":"%d"},"title":{"type":"SINGLE_LINE_TEXT","value":{}}}`, id)
+			}
+			return fmt.Sprintf(`{"$id":{"type":"__ID__","value":"%d"}}`, id)
+		}
+
+		switch {
+		case r.URL.Path == "/k/v1/records.json":
+			m := boundQuery.FindStringSubmatch(body.Query)
+			if m == nil {
+				t.Errorf("unexpected query: %s", body.Query)
+				return
+			}
+			var recs []string
+			for i := 1; i <= n; i++ {
+				id := i
+				if m[2] == "desc" {
+					id = n + 1 - i
+				}
+				if match(id, m[1]) {
+					recs = append(recs, record(id, false))
+					break
+				}
+			}
+			fmt.Fprintf(w, `{"records":[%s]}`, strings.Join(recs, ","))
+		case r.Method == "POST":
+			m := cursorQuery.FindStringSubmatch(body.Query)
+			if m == nil {
+				t.Errorf("unexpected query: %s", body.Query)
+				return
+			}
+			after, _ := strconv.Atoi(m[1])
+			until, _ := strconv.Atoi(m[2])
+			c := &cursor{size: body.Size, broken: m[3] == "broken"}
+			for id := after + 1; id <= until && id <= n; id++ {
+				if match(id, m[3]) {
+					c.ids = append(c.ids, id)
+				}
+			}
+			seq++
+			atomic.StoreInt32(&created, int32(seq))
+			id := strconv.Itoa(seq)
+			cursors[id] = c
+			if len(cursors) > peak {
+				peak = len(cursors)
+			}
+			fmt.Fprintf(w, `{"id":"%s","totalCount":"%d"}`, id, len(c.ids))
+		case r.Method == "GET":
+			id := r.URL.Query().Get("id")
+			c := cursors[id]
+			var recs []string
+			for len(c.ids) > 0 && len(recs) < c.size {
+				recs = append(recs, record(c.ids[0], c.broken))
+				c.ids = c.ids[1:]
+			}
+			if len(c.ids) == 0 {
+				delete(cursors, id)
+			}
+			fmt.Fprintf(w, `{"records":[%s],"next":%v}`, strings.Join(recs, ","), len(c.ids) > 0)
+		case r.Method == "DELETE":
+			delete(cursors, body.Id)
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	return app, &peak, &created
+}
+
+func TestParallelScanRecords(t *testing.T) {
+	app, peak, created := newParallelScanTestApp(t, 5000)
+
+	var ids []uint64
+	opts := &ParallelScanOptions{Workers: 3, Partitions: 7, Ordered: true, PageSize: 100}
+	err := app.ParallelScanRecords(nil, "odd", opts, func(recs []*Record) error {
+		if len(recs) > 100 {
+			t.Errorf("too large page: %d", len(recs))
+		}
+		// Ranges are (4999*i/7, 4999*(i+1)/7], read Workers ahead at most.
+		r := int32((recs[0].Id() - 1) * 7 / 4999)
+		if c := atomic.LoadInt32(created); c > r+3 {
+			t.Errorf("range %d must wait for range %d: %d cursors", c-1, c-4, c)
+		}
+		for _, rec := range recs {
+			ids = append(ids, rec.Id())
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2143 || ids[0] != 1 || ids[len(ids)-1] != 4999 {
+		t.Errorf("unexpected records: %d", len(ids))
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("records must be ordered: %d %d", ids[i-1], ids[i])
+		}
+	}
+	if *peak > 3 {
+		t.Errorf("too many cursors: %d", *peak)
+	}
+
+	seen := map[uint64]bool{}
+	err = app.ParallelScanRecords(nil, "", nil, func(recs []*Record) error {
+		for _, rec := range recs {
+			if seen[rec.Id()] {
+				t.Errorf("duplicate record: %d", rec.Id())
+			}
+			seen[rec.Id()] = true
+		}
+		return nil
+	})
+	if err != nil || len(seen) != 5000-5000/7 {
+		t.Errorf("unexpected result: %d %v", len(seen), err)
+	}
+
+	errStop := errors.New("stop")
+	err = app.ParallelScanRecords(nil, "", &ParallelScanOptions{PageSize: 10}, func(recs []*Record) error {
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParallelScanRecordsLenient(t *testing.T) {
+	app, _, _ := newParallelScanTestApp(t, 5000)
+	app.LenientDecoding = true
+	n := 0
+	err := app.ParallelScanRecords(nil, "broken", &ParallelScanOptions{Workers: 3}, func(recs []*Record) error {
+		n += len(recs)
+		return nil
+	})
+	var de *DecodeError
+	if !errors.As(err, &de) || len(de.Errors) != 5 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 5000-5000/7 {
+		t.Errorf("partially decoded records must be passed: %d", n)
+	}
+}
@@ -0,0 +1,101 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"context"
+)
+
+// UpdateRecordsStatus performs the process management action on recs.
+//
+// assignee and ignoreRevision work the same as UpdateRecordStatus.
+// Records are sent by chunks of 100 records, and the results are in the
+// order of recs.  Chunks are not atomic: if a chunk fails, the results
+// of the preceding chunks are returned with the error.  Revisions are
+// stored in recs if app.SyncRecords is set.
+func (app *App) UpdateRecordsStatus(recs []*Record, action *ProcessAction, assignee *Entity, ignoreRevision bool) ([]RecordResult, error) {
+	return app.UpdateRecordsStatusContext(context.Background(), recs, action, assignee, ignoreRevision)
+}
+
+// UpdateRecordsStatusContext is like UpdateRecordsStatus but uses ctx for the API requests.
+func (app *App) UpdateRecordsStatusContext(ctx context.Context, recs []*Record, action *ProcessAction, assignee *Entity, ignoreRevision bool) ([]RecordResult, error) {
+	results := make([]RecordResult, 0, len(recs))
+	for lo := 0; lo < len(recs); lo += bulkChunkSize {
+		chunk := recs[lo:min(lo+bulkChunkSize, len(recs))]
+		body, err := app.call(ctx, "PUT", "records/status", updateRecordsStatusRequest(app.AppId, chunk, action, assignee, ignoreRevision))
+		if err != nil {
+			return results, err
+		}
+		rs, err := app.updatedResults(body, chunk)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, rs...)
+	}
+	return results, nil
+}
+
+func updateRecordsStatusRequest(appId uint64, recs []*Record, action *ProcessAction, assignee *Entity, ignoreRevision bool) interface{} {
+	type record_t struct {
+		Id       uint64 `json:"id,string"`
+		Revision int64  `json:"revision,string"`
+		Action   string `json:"action"`
+		Assignee string `json:"assignee,omitempty"`
+	}
+	type request_body struct {
+		App     uint64     `json:"app,string"`
+		Records []record_t `json:"records"`
+	}
+	var code string
+	if assignee != nil {
+		code = assignee.Code
+	}
+	records := make([]record_t, len(recs))
+	for i, rec := range recs {
+		rev := rec.Revision()
+		if ignoreRevision {
+			rev = -1
+		}
+		records[i] = record_t{rec.id, rev, action.Name, code}
+	}
+	return request_body{appId, records}
+}
+
+// UpdateRecordAssignees replaces the assignees of the current status of
+// rec without performing an action.
+//
+// Up to 100 users can be assigned; assignees must be users as kintone
+// takes user codes only.  An empty assignees removes all of them.
+// ignoreRevision works the same as UpdateRecord method.
+func (app *App) UpdateRecordAssignees(rec *Record, assignees []*Entity, ignoreRevision bool) (*RecordResult, error) {
+	return app.UpdateRecordAssigneesContext(context.Background(), rec, assignees, ignoreRevision)
+}
+
+// UpdateRecordAssigneesContext is like UpdateRecordAssignees but uses ctx for the API request.
+func (app *App) UpdateRecordAssigneesContext(ctx context.Context, rec *Record, assignees []*Entity, ignoreRevision bool) (*RecordResult, error) {
+	if len(assignees) > 100 {
+		return nil, ErrTooMany
+	}
+
+	type request_body struct {
+		App       uint64   `json:"app,string"`
+		Id        uint64   `json:"id,string"`
+		Assignees []string `json:"assignees"`
+		Revision  int64    `json:"revision,string"`
+	}
+	codes := make([]string, len(assignees))
+	for i, e := range assignees {
+		codes[i] = e.Code
+	}
+	rev := rec.Revision()
+	if ignoreRevision {
+		rev = -1
+	}
+	body, err := app.call(ctx, "PUT", "record/assignees", request_body{app.AppId, rec.id, codes, rev})
+	if err != nil {
+		return nil, err
+	}
+	return app.revisionResult(body, rec)
+}
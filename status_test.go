@@ -0,0 +1,81 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUpdateRecordsStatus(t *testing.T) {
+	requests := &requestLog{}
+	app := newTestApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/k/v1/records/status.json" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		b := requests.addBody(r)
+		var p struct {
+			Records []struct {
+				Id string `json:"id"`
+			} `json:"records"`
+		}
+		json.Unmarshal(b, &p)
+		results := make([]string, len(p.Records))
+		for i, rec := range p.Records {
+			results[i] = fmt.Sprintf(`{"id":"%s","revision":"2"}`, rec.Id)
+		}
+		respond(http.StatusOK, fmt.Sprintf(`{"records":[%s]}`, strings.Join(results, ",")))(w, r)
+	}))
+	app.SyncRecords = true
+
+	recs := make([]*Record, 150)
+	for i := range recs {
+		recs[i] = NewRecordWithIdAndRevision(uint64(i+1), 1, nil)
+	}
+	results, err := app.UpdateRecordsStatus(recs, &ProcessAction{Name: "Approve"}, &Entity{Code: "user1"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 150 || results[149] != (RecordResult{150, 2}) || recs[0].Revision() != 2 {
+		t.Errorf("unexpected results: %d %v", len(results), results[149])
+	}
+	got := requests.all()
+	if len(got) != 2 {
+		t.Fatalf("unexpected requests: %d", len(got))
+	}
+	if !strings.HasPrefix(got[1], `{"app":"1","records":[{"id":"101","revision":"1","action":"Approve","assignee":"user1"},`) {
+		t.Errorf("unexpected request: %s", got[1])
+	}
+}
+
+func TestUpdateRecordAssignees(t *testing.T) {
+	requests := &requestLog{}
+	app := newTestApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/k/v1/record/assignees.json" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		requests.addBody(r)
+		respond(http.StatusOK, GetTestDataUpdateRecord().output)(w, r)
+	}))
+
+	rec := NewRecordWithIdAndRevision(5, 3, nil)
+	result, err := app.UpdateRecordAssignees(rec, []*Entity{{"USER", "user1"}, {"USER", "user2"}}, true)
+	if err != nil || *result != (RecordResult{5, 6}) {
+		t.Fatalf("unexpected result: %v %v", result, err)
+	}
+	expected := `{"app":"1","id":"5","assignees":["user1","user2"],"revision":"-1"}`
+	if got := requests.all(); len(got) != 1 || got[0] != expected {
+		t.Errorf("unexpected request: %v", got)
+	}
+	if rec.Revision() != 3 {
+		t.Error("records must not be synced by default")
+	}
+	if _, err := app.UpdateRecordAssignees(rec, make([]*Entity, 101), false); err != ErrTooMany {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
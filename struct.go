@@ -0,0 +1,574 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MappingError reports a field which could not be mapped between a
+// record and a struct.
+type MappingError struct {
+	FieldCode string       // Field code.
+	FieldType string       // Field type such as "NUMBER".
+	GoType    reflect.Type // Type of the struct field.
+	Err       error
+}
+
+func (e *MappingError) Error() string {
+	if len(e.FieldType) == 0 {
+		return fmt.Sprintf("kintone: cannot map field %q to %v: %v",
+			e.FieldCode, e.GoType, e.Err)
+	}
+	return fmt.Sprintf("kintone: cannot map %s field %q to %v: %v",
+		e.FieldType, e.FieldCode, e.GoType, e.Err)
+}
+
+func (e *MappingError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	jsonValueType = reflect.TypeOf((*UpdateKeyField)(nil)).Elem()
+)
+
+// fieldTypeOf returns the field type of f, a field in Record.Fields.
+func fieldTypeOf(f interface{}) string {
+	switch f := f.(type) {
+	case SingleLineTextField:
+		return FT_SINGLE_LINE_TEXT
+	case MultiLineTextField:
+		return FT_MULTI_LINE_TEXT
+	case RichTextField:
+		return FT_RICH_TEXT
+	case DecimalField:
+		return FT_DECIMAL
+	case CalcField:
+		return FT_CALC
+	case CheckBoxField:
+		return FT_CHECK_BOX
+	case RadioButtonField:
+		return FT_RADIO
+	case SingleSelectField:
+		return FT_SINGLE_SELECT
+	case MultiSelectField:
+		return FT_MULTI_SELECT
+	case FileField:
+		return FT_FILE
+	case LinkField:
+		return FT_LINK
+	case DateField:
+		return FT_DATE
+	case TimeField:
+		return FT_TIME
+	case DateTimeField:
+		return FT_DATETIME
+	case UserField:
+		return FT_USER
+	case OrganizationField:
+		return FT_ORGANIZATION
+	case GroupField:
+		return FT_GROUP
+	case CategoryField:
+		return FT_CATEGORY
+	case StatusField:
+		return FT_STATUS
+	case AssigneeField:
+		return FT_ASSIGNEE
+	case RecordNumberField:
+		return FT_RECNUM
+	case CreatorField:
+		return FT_CREATOR
+	case CreationTimeField:
+		return FT_CTIME
+	case ModifierField:
+		return FT_MODIFIER
+	case ModificationTimeField:
+		return FT_MTIME
+	case SubTableField:
+		return FT_SUBTABLE
+	case RawField:
+		return f.Type
+	}
+	return ""
+}
+
+// structField is a struct field mapped to a kintone field.
+type structField struct {
+	index     int
+	code      string // Field code, "$id" or "$revision".
+	fieldType string // Field type given by the tag, if any.
+}
+
+// structFields returns the fields of t which have the "kintone" tag.
+//
+// The tag is the field code optionally followed by the field type:
+//
+//	Title string    `kintone:"title"`
+//	Due   time.Time `kintone:"due,DATE"`
+func structFields(t reflect.Type) []structField {
+	var sfs []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("kintone")
+		if !ok || tag == "-" || len(f.PkgPath) > 0 {
+			continue
+		}
+		code, ft, _ := strings.Cut(tag, ",")
+		if len(code) == 0 {
+			code = f.Name
+		}
+		sfs = append(sfs, structField{i, code, ft})
+	}
+	return sfs
+}
+
+// structValue returns the struct v points to.
+func structValue(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("kintone: %T is not a struct", v)
+	}
+	return rv, nil
+}
+
+// UnmarshalRecord stores the fields of rec in the struct pointed to by v.
+//
+// Struct fields are mapped to kintone fields by the "kintone" tag
+// whose value is the field code.  "$id" and "$revision" map the record
+// ID and revision to integers.  A kintone field can be stored in
+//   - a string, or an integer or a float for numeric values,
+//   - time.Time for dates, times and datetimes,
+//   - []string for check boxes, multi-select boxes and categories,
+//   - []User, []Organization, []Group, User or []File,
+//   - a slice of tagged structs for subtables,
+//   - a field type of this package or interface{},
+//   - a pointer to one of them, which is nil if the field is empty.
+//
+// Fields missing in rec are left unchanged.  A field which cannot be
+// stored is reported by *MappingError.
+func UnmarshalRecord(rec *Record, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("kintone: UnmarshalRecord needs a pointer to a struct, not %T", v)
+	}
+	return unmarshalRecord(rec, rv.Elem())
+}
+
+func unmarshalRecord(rec *Record, sv reflect.Value) error {
+	for _, sf := range structFields(sv.Type()) {
+		fv := sv.Field(sf.index)
+		var f interface{}
+		switch sf.code {
+		case "$id":
+			f = DecimalField(strconv.FormatUint(rec.id, 10))
+		case "$revision":
+			f = DecimalField(strconv.FormatInt(rec.revision, 10))
+		default:
+			var ok bool
+			if f, ok = rec.Fields[sf.code]; !ok {
+				continue
+			}
+		}
+		if err := unmarshalField(f, fv); err != nil {
+			if _, ok := err.(*MappingError); ok {
+				return err
+			}
+			return &MappingError{sf.code, fieldTypeOf(f), fv.Type(), err}
+		}
+	}
+	return nil
+}
+
+// jsonValue returns the value of f in the JSON representation.
+func jsonValue(f interface{}) interface{} {
+	if jv, ok := f.(UpdateKeyField); ok {
+		return jv.JSONValue()
+	}
+	return f
+}
+
+// unmarshalField stores f in v.
+func unmarshalField(f interface{}, v reflect.Value) error {
+	if f == nil {
+		return errors.New("nil field")
+	}
+	fv := reflect.ValueOf(f)
+	if fv.Type().AssignableTo(v.Type()) {
+		v.Set(fv)
+		return nil
+	}
+
+	jv := jsonValue(f)
+	if v.Kind() == reflect.Ptr {
+		ek := v.Type().Elem().Kind()
+		if jv == nil || (jv == "" && ek != reflect.String) {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		p := reflect.New(v.Type().Elem())
+		if err := unmarshalField(f, p.Elem()); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	if v.Type() == timeType {
+		var t time.Time
+		switch f := f.(type) {
+		case DateField:
+			t = f.Date
+		case TimeField:
+			t = f.Time
+		case DateTimeField:
+			t = f.Time
+		case CreationTimeField:
+			t = time.Time(f)
+		case ModificationTimeField:
+			t = time.Time(f)
+		default:
+			return errors.New("not a date or time field")
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	if st, ok := f.(SubTableField); ok {
+		if v.Kind() != reflect.Slice {
+			return errors.New("subtable needs a slice")
+		}
+		et := v.Type().Elem()
+		isPtr := et.Kind() == reflect.Ptr
+		if isPtr {
+			et = et.Elem()
+		}
+		if et.Kind() != reflect.Struct {
+			return errors.New("subtable needs a slice of structs")
+		}
+		rows := reflect.MakeSlice(v.Type(), len(st), len(st))
+		for i, row := range st {
+			rv := reflect.New(et)
+			if err := unmarshalRecord(row, rv.Elem()); err != nil {
+				return err
+			}
+			if isPtr {
+				rows.Index(i).Set(rv)
+			} else {
+				rows.Index(i).Set(rv.Elem())
+			}
+		}
+		v.Set(rows)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		s, ok := jv.(string)
+		if !ok {
+			return errors.New("not a numeric field")
+		}
+		if len(s) == 0 {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			n, err := strconv.ParseFloat(s, v.Type().Bits())
+			if err != nil {
+				return err
+			}
+			v.SetFloat(n)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+			if err != nil {
+				return err
+			}
+			v.SetUint(n)
+		default:
+			n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+			if err != nil {
+				return err
+			}
+			v.SetInt(n)
+		}
+		return nil
+	}
+
+	b, err := json.Marshal(jv)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v.Addr().Interface())
+}
+
+// MarshalRecord creates a record from v, a struct or a pointer to a
+// struct tagged as described in UnmarshalRecord.
+//
+// The field type is taken from the tag if given, or inferred from the
+// Go type: SINGLE_LINE_TEXT for strings, NUMBER for integers and floats,
+// DATETIME for time.Time, CHECK_BOX for []string, USER_SELECT for []User,
+// ORGANIZATION_SELECT, GROUP_SELECT, FILE, CREATOR for User and SUBTABLE
+// for slices of structs.  Nil pointers become empty fields and values of field types
+// in this package are used as is.
+func MarshalRecord(v interface{}) (*Record, error) {
+	sv, err := structValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return marshalRecord(sv)
+}
+
+func marshalRecord(sv reflect.Value) (*Record, error) {
+	rec := NewRecord(make(map[string]interface{}))
+	for _, sf := range structFields(sv.Type()) {
+		fv := sv.Field(sf.index)
+		switch sf.code {
+		case "$id", "$revision":
+			for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			var n int64
+			switch fv.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				n = fv.Int()
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				n = int64(fv.Uint())
+			case reflect.Ptr:
+				continue
+			default:
+				return nil, &MappingError{sf.code, sf.code, fv.Type(), errors.New("not an integer")}
+			}
+			if sf.code == "$id" {
+				rec.id = uint64(n)
+			} else {
+				rec.revision = n
+			}
+			continue
+		}
+		f, err := marshalField(fv, sf.fieldType)
+		if err != nil {
+			if _, ok := err.(*MappingError); ok {
+				return nil, err
+			}
+			ft := sf.fieldType
+			if len(ft) == 0 {
+				ft = inferFieldType(fv.Type())
+			}
+			return nil, &MappingError{sf.code, ft, fv.Type(), err}
+		}
+		if f != nil {
+			rec.Fields[sf.code] = f
+		}
+	}
+	return rec, nil
+}
+
+// inferFieldType returns the default field type for values of t.
+func inferFieldType(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		return FT_DATETIME
+	case reflect.TypeOf([]User(nil)):
+		return FT_USER
+	case reflect.TypeOf([]Organization(nil)):
+		return FT_ORGANIZATION
+	case reflect.TypeOf([]Group(nil)):
+		return FT_GROUP
+	case reflect.TypeOf([]File(nil)):
+		return FT_FILE
+	case reflect.TypeOf(User{}):
+		return FT_CREATOR
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return FT_SINGLE_LINE_TEXT
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return FT_DECIMAL
+	case reflect.Slice:
+		et := t.Elem()
+		if et.Kind() == reflect.Ptr {
+			et = et.Elem()
+		}
+		if et.Kind() == reflect.String {
+			return FT_CHECK_BOX
+		}
+		if et.Kind() == reflect.Struct {
+			return FT_SUBTABLE
+		}
+	}
+	return ""
+}
+
+// marshalField converts v into a field of type ft.  It returns nil for
+// nil interfaces.
+func marshalField(v reflect.Value, ft string) (interface{}, error) {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Type().Implements(jsonValueType) {
+		return v.Interface(), nil
+	}
+	if len(ft) == 0 {
+		if ft = inferFieldType(v.Type()); len(ft) == 0 {
+			return nil, errors.New("unknown field type")
+		}
+	}
+
+	if ft == FT_SUBTABLE {
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Slice {
+			return nil, errors.New("subtable needs a slice")
+		}
+		st := make(SubTableField, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			rv := v.Index(i)
+			for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+				rv = rv.Elem()
+			}
+			if rv.Kind() != reflect.Struct {
+				continue
+			}
+			row, err := marshalRecord(rv)
+			if err != nil {
+				return nil, err
+			}
+			st = append(st, row)
+		}
+		return st, nil
+	}
+
+	var jv interface{}
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+	case reflect.String:
+		jv = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		jv = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		jv = strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		jv = strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	default:
+		if t, ok := v.Interface().(time.Time); ok {
+			switch {
+			case t.IsZero():
+			case ft == FT_DATE:
+				jv = t.Format("2006-01-02")
+			case ft == FT_TIME:
+				jv = t.Format("15:04")
+			default:
+				jv = t.Format(time.RFC3339)
+			}
+		} else {
+			jv = v.Interface()
+		}
+	}
+	b, err := json.Marshal(jv)
+	if err != nil {
+		return nil, err
+	}
+	return (&Decoder{}).decodeField(&Record{}, "", fieldData{ft, b})
+}
+
+// GetRecordAs is like App.GetRecord but stores the record in a new T,
+// a struct tagged as described in UnmarshalRecord.
+func GetRecordAs[T any](app *App, id uint64) (*T, error) {
+	return GetRecordAsContext[T](context.Background(), app, id)
+}
+
+// GetRecordAsContext is like GetRecordAs but uses ctx for the API request.
+func GetRecordAsContext[T any](ctx context.Context, app *App, id uint64) (*T, error) {
+	rec, err := app.GetRecordContext(ctx, id)
+	if rec == nil {
+		return nil, err
+	}
+	v := new(T)
+	if err := UnmarshalRecord(rec, v); err != nil {
+		return nil, err
+	}
+	return v, err
+}
+
+// GetRecordsAs is like App.GetRecords but stores the records in T,
+// a struct tagged as described in UnmarshalRecord.
+func GetRecordsAs[T any](app *App, fields []string, query string) ([]T, error) {
+	return GetRecordsAsContext[T](context.Background(), app, fields, query)
+}
+
+// GetRecordsAsContext is like GetRecordsAs but uses ctx for the API request.
+func GetRecordsAsContext[T any](ctx context.Context, app *App, fields []string, query string) ([]T, error) {
+	recs, err := app.GetRecordsContext(ctx, fields, query)
+	if recs == nil {
+		return nil, err
+	}
+	vs := make([]T, len(recs))
+	for i, rec := range recs {
+		if err := UnmarshalRecord(rec, &vs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return vs, err
+}
+
+// AddRecordAs is like App.AddRecord but adds a record made from v
+// by MarshalRecord.
+func AddRecordAs[T any](app *App, v T) (string, error) {
+	return AddRecordAsContext(context.Background(), app, v)
+}
+
+// AddRecordAsContext is like AddRecordAs but uses ctx for the API request.
+func AddRecordAsContext[T any](ctx context.Context, app *App, v T) (string, error) {
+	rec, err := MarshalRecord(v)
+	if err != nil {
+		return "", err
+	}
+	return app.AddRecordContext(ctx, rec)
+}
+
+// AddRecordsAs is like App.AddRecords but adds records made from vs
+// by MarshalRecord.
+func AddRecordsAs[T any](app *App, vs []T) ([]string, error) {
+	return AddRecordsAsContext(context.Background(), app, vs)
+}
+
+// AddRecordsAsContext is like AddRecordsAs but uses ctx for the API request.
+func AddRecordsAsContext[T any](ctx context.Context, app *App, vs []T) ([]string, error) {
+	recs := make([]*Record, len(vs))
+	for i, v := range vs {
+		rec, err := MarshalRecord(v)
+		if err != nil {
+			return nil, err
+		}
+		recs[i] = rec
+	}
+	return app.AddRecordsContext(ctx, recs)
+}
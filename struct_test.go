@@ -0,0 +1,182 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testItem struct {
+	Id       uint64     `kintone:"$id"`
+	Revision int64      `kintone:"$revision"`
+	Title    string     `kintone:"title"`
+	Price    float64    `kintone:"price"`
+	Count    *int       `kintone:"count"`
+	Due      time.Time  `kintone:"due,DATE"`
+	Alarm    *time.Time `kintone:"alarm,DATETIME"`
+	Tags     []string   `kintone:"tags,MULTI_SELECT"`
+	Owners   []User     `kintone:"owners"`
+	Creator  User       `kintone:"creator,CREATOR"`
+	Lines    []testLine `kintone:"lines"`
+	Status   StatusField
+	ignored  string `kintone:"ignored"`
+}
+
+type testLine struct {
+	Id  uint64 `kintone:"$id"`
+	Qty int    `kintone:"qty"`
+}
+
+func TestUnmarshalRecord(t *testing.T) {
+	t.Parallel()
+
+	rec, err := DecodeRecord([]byte(`{"record": {
+		"$id": {"type": "__ID__", "value": "3"},
+		"$revision": {"type": "__REVISION__", "value": "7"},
+		"title": {"type": "SINGLE_LINE_TEXT", "value": "hoge"},
+		"price": {"type": "NUMBER", "value": "12.5"},
+		"count": {"type": "NUMBER", "value": ""},
+		"due": {"type": "DATE", "value": "2019-03-11"},
+		"alarm": {"type": "DATETIME", "value": null},
+		"tags": {"type": "MULTI_SELECT", "value": ["a", "b"]},
+		"owners": {"type": "USER_SELECT", "value": [{"code": "sato", "name": "Noboru Sato"}]},
+		"creator": {"type": "CREATOR", "value": {"code": "sato", "name": "Noboru Sato"}},
+		"lines": {"type": "SUBTABLE", "value": [{"id": "10", "value": {"qty": {"type": "NUMBER", "value": "2"}}}]}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var item testItem
+	if err := UnmarshalRecord(rec, &item); err != nil {
+		t.Fatal(err)
+	}
+	expected := testItem{
+		Id:       3,
+		Revision: 7,
+		Title:    "hoge",
+		Price:    12.5,
+		Due:      time.Date(2019, 3, 11, 0, 0, 0, 0, time.UTC),
+		Tags:     []string{"a", "b"},
+		Owners:   []User{{"sato", "Noboru Sato"}},
+		Creator:  User{"sato", "Noboru Sato"},
+		Lines:    []testLine{{10, 2}},
+	}
+	if !reflect.DeepEqual(item, expected) {
+		t.Errorf("unexpected item: %+v", item)
+	}
+
+	var bad struct {
+		Title int `kintone:"title"`
+	}
+	err = UnmarshalRecord(rec, &bad)
+	var me *MappingError
+	if !errors.As(err, &me) || me.FieldCode != "title" || me.FieldType != FT_SINGLE_LINE_TEXT {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := UnmarshalRecord(rec, bad); err == nil {
+		t.Error("UnmarshalRecord must fail for non-pointers")
+	}
+}
+
+func TestMarshalRecord(t *testing.T) {
+	t.Parallel()
+
+	count := 5
+	item := testItem{
+		Id:       3,
+		Revision: 7,
+		Title:    "hoge",
+		Price:    12.5,
+		Count:    &count,
+		Due:      time.Date(2019, 3, 11, 0, 0, 0, 0, time.UTC),
+		Tags:     []string{"a"},
+		Lines:    []testLine{{10, 2}, {0, 3}},
+	}
+	rec, err := MarshalRecord(&item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Id() != 3 || rec.Revision() != 7 {
+		t.Errorf("unexpected id and revision: %d %d", rec.Id(), rec.Revision())
+	}
+	expected := map[string]interface{}{
+		"title":   SingleLineTextField("hoge"),
+		"price":   DecimalField("12.5"),
+		"count":   DecimalField("5"),
+		"due":     NewDateField(2019, time.March, 11),
+		"alarm":   DateTimeField{Valid: false},
+		"tags":    MultiSelectField{"a"},
+		"owners":  UserField(nil),
+		"creator": CreatorField{},
+		"lines": SubTableField{
+			NewRecordWithId(10, map[string]interface{}{"qty": DecimalField("2")}),
+			NewRecord(map[string]interface{}{"qty": DecimalField("3")}),
+		},
+	}
+	if !reflect.DeepEqual(rec.Fields, expected) {
+		b, _ := json.Marshal(rec.Fields)
+		t.Errorf("unexpected fields: %s", b)
+	}
+
+	var decoded testItem
+	if err := UnmarshalRecord(rec, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, item) {
+		t.Errorf("item must round-trip: %+v", decoded)
+	}
+
+	var bad struct {
+		C chan int `kintone:"c"`
+	}
+	var me *MappingError
+	if _, err := MarshalRecord(bad); !errors.As(err, &me) || me.FieldCode != "c" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGetRecordsAs(t *testing.T) {
+	var sent []byte
+	app := newApp()
+	app.Middlewares = []Middleware{func(next APIHandler) APIHandler {
+		return func(ctx context.Context, call *APICall) (*APIResult, error) {
+			if call.Method == "POST" {
+				sent, _ = json.Marshal(call.Body)
+				return &APIResult{StatusCode: 200, Body: []byte(`{"ids":["5"],"revisions":["1"]}`)}, nil
+			}
+			return next(ctx, call)
+		}
+	}}
+
+	type item struct {
+		Id      uint64    `kintone:"$id"`
+		Created time.Time `kintone:"Created_datetime"`
+		Creator User      `kintone:"Created_by"`
+		Title   string    `kintone:"title"`
+	}
+	items, err := GetRecordsAs[item](app, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[1].Id != 2 || items[1].Creator.Code != "Administrator" ||
+		!items[1].Created.Equal(time.Date(2019, 3, 11, 6, 42, 0, 0, time.UTC)) {
+		t.Errorf("unexpected items: %+v", items)
+	}
+
+	ids, err := AddRecordsAs(app, []item{{Title: "x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"app":"1","records":[{"Created_by":{"type":"CREATOR","value":{"code":"","name":""}},` +
+		`"Created_datetime":{"type":"DATETIME","value":null},"title":{"type":"SINGLE_LINE_TEXT","value":"x"}}]}`
+	if len(ids) != 1 || string(sent) != expected {
+		t.Errorf("unexpected request: %s", sent)
+	}
+}
@@ -0,0 +1,250 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// MAX_TRANSACTION_OPS is the maximum number of operations in a Transaction.
+const MAX_TRANSACTION_OPS = 20
+
+// Transaction queues record writes, possibly to different applications,
+// and commits them atomically with the bulkRequest API.  Either all of
+// the operations are applied or none of them is.
+//
+// Operations are sent with the credentials of the App or Client which
+// created the transaction.  To write to several applications with API
+// tokens, set the tokens of all of them separated by commas.
+//
+// ex: move a record to another application
+//
+//	tx := client.NewTransaction()
+//	tx.AddRecords(archiveId, []*kintone.Record{rec})
+//	tx.DeleteRecords(appId, []uint64{rec.Id()})
+//	results, err := tx.Commit()
+type Transaction struct {
+	app *App
+	ops []txOp
+}
+
+// txOp is an operation queued in a Transaction.
+type txOp struct {
+	method  string
+	api     string
+	payload func() (interface{}, error) // Builds the request body at commit.
+	size    int                         // Number of records.
+	recs    []*Record                   // Written records.
+	update  bool                        // recs are updated, so that their changes are sent.
+	id      uint64                      // ID of the record whose status is updated.
+}
+
+// OperationResult is the result of an operation in a committed Transaction.
+type OperationResult struct {
+	Ids       []uint64 // IDs of the added, updated or advanced records.  Empty for deletions.
+	Revisions []int64  // Revisions of the records after the operation.
+}
+
+// TransactionError reports the operation which made a transaction fail.
+// None of the operations in the transaction was applied.
+type TransactionError struct {
+	Index int       // Index of the failed operation.
+	Err   *AppError // Error of the operation.
+}
+
+func (e *TransactionError) Error() string {
+	return fmt.Sprintf("kintone: operation %d of the transaction failed: %v", e.Index, e.Err)
+}
+
+func (e *TransactionError) Unwrap() error {
+	return e.Err
+}
+
+// NewTransaction returns an empty transaction committed through app.
+//
+// Operations name their applications explicitly; the ID of app is not used.
+func (app *App) NewTransaction() *Transaction {
+	return &Transaction{app: app}
+}
+
+// NewTransaction returns an empty transaction committed through c.
+func (c *Client) NewTransaction() *Transaction {
+	return c.App(0).NewTransaction()
+}
+
+// queue appends an operation and returns its index.
+func (tx *Transaction) queue(op txOp) int {
+	tx.ops = append(tx.ops, op)
+	return len(tx.ops) - 1
+}
+
+// AddRecords queues addition of recs to the application appId, and
+// returns the index of the operation.
+//
+// Records are read at commit, so that changes made to them until then
+// are sent.  The same applies to the other operations.
+func (tx *Transaction) AddRecords(appId uint64, recs []*Record) int {
+	return tx.queue(txOp{"POST", "records", func() (interface{}, error) {
+		return addRecordsRequest(appId, recs), nil
+	}, len(recs), recs, false, 0})
+}
+
+// UpdateRecords queues update of recs in the application appId, and
+// returns the index of the operation.  See App.UpdateRecords.
+func (tx *Transaction) UpdateRecords(appId uint64, recs []*Record, ignoreRevision bool) int {
+	return tx.queue(txOp{"PUT", "records", func() (interface{}, error) {
+		return tx.app.updateRecordsRequest(appId, recs, ignoreRevision), nil
+	}, len(recs), recs, true, 0})
+}
+
+// UpdateRecordsByKey queues update of recs identified by keyField in
+// the application appId, and returns the index of the operation.  See
+// App.UpdateRecordsByKey.
+func (tx *Transaction) UpdateRecordsByKey(appId uint64, recs []*Record, ignoreRevision bool, keyField string) int {
+	return tx.queue(txOp{"PUT", "records", func() (interface{}, error) {
+		return tx.app.updateRecordsByKeyRequest(appId, recs, ignoreRevision, keyField, false)
+	}, len(recs), recs, true, 0})
+}
+
+// DeleteRecords queues deletion of records from the application appId,
+// and returns the index of the operation.
+func (tx *Transaction) DeleteRecords(appId uint64, ids []uint64) int {
+	return tx.queue(txOp{"DELETE", "records", func() (interface{}, error) {
+		return deleteRecordsRequest(appId, ids), nil
+	}, len(ids), nil, false, 0})
+}
+
+// UpdateRecordStatus queues a process management action on rec in the
+// application appId, and returns the index of the operation.  See
+// App.UpdateRecordStatus.
+func (tx *Transaction) UpdateRecordStatus(appId uint64, rec *Record, action *ProcessAction, assignee *Entity, ignoreRevision bool) int {
+	return tx.queue(txOp{"PUT", "record/status", func() (interface{}, error) {
+		return updateRecordStatusRequest(appId, rec, action, assignee, ignoreRevision), nil
+	}, 1, []*Record{rec}, false, rec.id})
+}
+
+// Len returns the number of queued operations.
+func (tx *Transaction) Len() int {
+	return len(tx.ops)
+}
+
+// Commit sends the queued operations in one request.
+//
+// It returns the results in the order of the operations.  If an
+// operation fails, the error is *TransactionError which reports the
+// operation.  ErrTooMany is returned without sending the request for
+// more than MAX_TRANSACTION_OPS operations or an operation of more
+// than 100 records, and ErrInvalidKeyField for a record without its key.
+func (tx *Transaction) Commit() ([]OperationResult, error) {
+	return tx.CommitContext(context.Background())
+}
+
+// CommitContext is like Commit but uses ctx for the API request.
+func (tx *Transaction) CommitContext(ctx context.Context) ([]OperationResult, error) {
+	if len(tx.ops) == 0 {
+		return nil, nil
+	}
+	if len(tx.ops) > MAX_TRANSACTION_OPS {
+		return nil, ErrTooMany
+	}
+
+	type request_t struct {
+		Method  string      `json:"method"`
+		Api     string      `json:"api"`
+		Payload interface{} `json:"payload"`
+	}
+	type request_body struct {
+		Requests []request_t `json:"requests"`
+	}
+	reqs := make([]request_t, 0, len(tx.ops))
+	for i, op := range tx.ops {
+		if op.size > 100 {
+			return nil, ErrTooMany
+		}
+		payload, err := op.payload()
+		if err != nil {
+			return nil, fmt.Errorf("kintone: operation %d of the transaction: %w", i, err)
+		}
+		reqs = append(reqs, request_t{op.method, tx.app.apiPath(op.api), payload})
+	}
+	body, err := tx.app.call(ctx, "POST", "bulkRequest", request_body{reqs})
+	if err != nil {
+		return nil, tx.operationError(err)
+	}
+
+	var t struct {
+		Results []struct {
+			Ids       []string `json:"ids"`
+			Revisions []string `json:"revisions"`
+			Records   []struct {
+				Id       string `json:"id"`
+				Revision string `json:"revision"`
+			} `json:"records"`
+			Revision string `json:"revision"`
+		} `json:"results"`
+	}
+	if json.Unmarshal(body, &t) != nil || len(t.Results) != len(tx.ops) {
+		return nil, ErrInvalidResponse
+	}
+	results := make([]OperationResult, len(tx.ops))
+	for i, r := range t.Results {
+		ids, revisions := r.Ids, r.Revisions
+		for _, rec := range r.Records {
+			ids = append(ids, rec.Id)
+			revisions = append(revisions, rec.Revision)
+		}
+		res := &results[i]
+		if len(r.Revision) > 0 {
+			res.Ids = []uint64{tx.ops[i].id}
+			revisions = append(revisions, r.Revision)
+		}
+		for _, s := range ids {
+			id, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				return nil, ErrInvalidResponse
+			}
+			res.Ids = append(res.Ids, id)
+		}
+		for _, s := range revisions {
+			rev, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, ErrInvalidResponse
+			}
+			res.Revisions = append(res.Revisions, rev)
+		}
+	}
+	for i, op := range tx.ops {
+		for k, rec := range op.recs {
+			if op.update {
+				rec.takeSnapshot()
+			}
+			if tx.app.SyncRecords && k < len(results[i].Ids) && k < len(results[i].Revisions) {
+				syncRecord(rec, RecordResult{results[i].Ids[k], results[i].Revisions[k]})
+			}
+		}
+	}
+	return results, nil
+}
+
+// operationError converts an error response of bulkRequest into
+// *TransactionError.  The result of the failed operation has the error
+// while the others are empty.
+func (tx *Transaction) operationError(err error) error {
+	ae, ok := err.(*AppError)
+	if !ok {
+		return err
+	}
+	for i, raw := range ae.results {
+		oe, derr := decodeAppError(ae.HttpStatus, ae.HttpStatusCode, raw)
+		if derr != nil || (len(oe.Code) == 0 && len(oe.Message) == 0) {
+			continue
+		}
+		return &TransactionError{i, oe}
+	}
+	return err
+}
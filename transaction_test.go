@@ -0,0 +1,114 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// newTransactionTestApp returns an app whose bulkRequest API responds
+// with status and body.  Request bodies are recorded in requests.
+func newTransactionTestApp(t *testing.T, status int, body string) (*App, *requestLog) {
+	requests := &requestLog{}
+	app := newTestApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/k/guest/3/v1/bulkRequest.json" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		requests.addBody(r)
+		respond(status, body)(w, r)
+	}))
+	app.GuestSpaceId = 3
+	return app, requests
+}
+
+func TestTransaction(t *testing.T) {
+	app, requests := newTransactionTestApp(t, http.StatusOK, `{"results":[
+		{"ids":["10","11"],"revisions":["1","1"]},
+		{"records":[{"id":"3","revision":"8"}]},
+		{},
+		{"revision":"5"}
+	]}`)
+	rec := NewRecordWithIdAndRevision(3, 7, map[string]interface{}{
+		"title": SingleLineTextField("hoge"),
+	})
+	tx := app.NewTransaction()
+	if i := tx.AddRecords(5, []*Record{NewRecord(map[string]interface{}{}), NewRecord(map[string]interface{}{})}); i != 0 {
+		t.Errorf("unexpected index: %d", i)
+	}
+	tx.UpdateRecords(1, []*Record{rec}, false)
+	rec.Fields["title"] = SingleLineTextField("fuga")
+	tx.DeleteRecords(5, []uint64{4})
+	if i := tx.UpdateRecordStatus(1, rec, &ProcessAction{Name: "Start"}, nil, true); i != 3 {
+		t.Errorf("unexpected index: %d", i)
+	}
+	results, err := tx.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []OperationResult{
+		{[]uint64{10, 11}, []int64{1, 1}},
+		{[]uint64{3}, []int64{8}},
+		{nil, nil},
+		{[]uint64{3}, []int64{5}},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("unexpected results: %v", results)
+	}
+	request := `{"requests":[` +
+		`{"method":"POST","api":"/k/guest/3/v1/records.json","payload":{"app":"5","records":[{},{}]}},` +
+		`{"method":"PUT","api":"/k/guest/3/v1/records.json","payload":{"app":"1","records":[{"id":"3","revision":"7","record":{"title":{"type":"SINGLE_LINE_TEXT","value":"fuga"}}}]}},` +
+		`{"method":"DELETE","api":"/k/guest/3/v1/records.json","payload":{"app":"5","ids":[4]}},` +
+		`{"method":"PUT","api":"/k/guest/3/v1/record/status.json","payload":{"app":"1","id":"3","revision":"-1","action":"Start"}}]}`
+	if got := requests.all(); len(got) != 1 || got[0] != request {
+		t.Errorf("unexpected request: %v", got)
+	}
+	if len(rec.ChangedFields()) != 0 {
+		t.Error("updated records must not have changes")
+	}
+}
+
+func TestTransactionError(t *testing.T) {
+	app, requests := newTransactionTestApp(t, http.StatusConflict, `{"results":[
+		{},
+		`+GetTestDataRevisionConflict().output+`,
+		{}
+	]}`)
+	tx := app.NewTransaction()
+	for i := 0; i < 3; i++ {
+		tx.DeleteRecords(1, []uint64{uint64(i + 1)})
+	}
+	_, err := tx.Commit()
+	var te *TransactionError
+	if !errors.As(err, &te) || te.Index != 1 || te.Err.Code != "GAIA_CO02" || te.Err.HttpStatusCode != 409 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !errors.Is(err, ErrRevisionConflict) {
+		t.Error("the error of the operation must be classified")
+	}
+
+	for tx.Len() <= MAX_TRANSACTION_OPS {
+		tx.DeleteRecords(1, []uint64{1})
+	}
+	if _, err := tx.Commit(); err != ErrTooMany {
+		t.Errorf("unexpected error: %v", err)
+	}
+	tx = app.NewTransaction()
+	tx.DeleteRecords(1, make([]uint64, 101))
+	if _, err := tx.Commit(); err != ErrTooMany {
+		t.Errorf("unexpected error: %v", err)
+	}
+	tx = app.NewTransaction()
+	tx.DeleteRecords(1, []uint64{1})
+	tx.UpdateRecordsByKey(1, []*Record{NewRecord(map[string]interface{}{"key": nil})}, false, "key")
+	if _, err := tx.Commit(); !errors.Is(err, ErrInvalidKeyField) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := requests.all(); len(got) != 1 {
+		t.Errorf("unexpected requests: %d", len(got))
+	}
+}
@@ -0,0 +1,234 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// UpsertResult is the result of an upsert of a record.
+type UpsertResult struct {
+	Id       uint64 // ID of the record.
+	Revision int64  // Revision of the record after the upsert.
+	Created  bool   // true if the record was added, false if updated.
+}
+
+// upsertResult is an upsert result in API responses.
+type upsertResult struct {
+	Id        string `json:"id"`
+	Revision  string `json:"revision"`
+	Operation string `json:"operation"` // "INSERT" or "UPDATE"
+}
+
+func (r upsertResult) result() (UpsertResult, error) {
+	id, err := strconv.ParseUint(r.Id, 10, 64)
+	if err != nil {
+		return UpsertResult{}, ErrInvalidResponse
+	}
+	revision, err := strconv.ParseInt(r.Revision, 10, 64)
+	if err != nil {
+		return UpsertResult{}, ErrInvalidResponse
+	}
+	return UpsertResult{id, revision, r.Operation == "INSERT"}, nil
+}
+
+// UpsertRecord updates the record whose keyField has the same value as
+// rec, or adds rec if there is no such record.
+//
+// keyField must be a SINGLE_LINE_TEXT or NUMBER field which prohibits
+// duplicate values.  It is checked with Fields before rec is sent, and
+// ErrInvalidKeyField is returned otherwise.  Apps created by NewApp or
+// by a Client of NewClient remember valid key fields for ten minutes,
+// and check them again after a failed upsert.  ignoreRevision works the
+// same as UpdateRecord method.
+func (app *App) UpsertRecord(rec *Record, ignoreRevision bool, keyField string) (*UpsertResult, error) {
+	return app.UpsertRecordContext(context.Background(), rec, ignoreRevision, keyField)
+}
+
+// UpsertRecordContext is like UpsertRecord but uses ctx for the API requests.
+func (app *App) UpsertRecordContext(ctx context.Context, rec *Record, ignoreRevision bool, keyField string) (*UpsertResult, error) {
+	if err := app.checkKeyField(ctx, []*Record{rec}, keyField); err != nil {
+		return nil, err
+	}
+	req, err := app.updateRecordByKeyRequest(rec, ignoreRevision, keyField, true)
+	if err != nil {
+		return nil, err
+	}
+	body, err := app.call(ctx, "PUT", "record", req)
+	if err != nil {
+		app.forgetKeyField(keyField)
+		return nil, err
+	}
+
+	var t upsertResult
+	if json.Unmarshal(body, &t) != nil {
+		return nil, ErrInvalidResponse
+	}
+	result, err := t.result()
+	if err != nil {
+		return nil, err
+	}
+	rec.takeSnapshot()
+	if app.SyncRecords {
+		syncRecord(rec, RecordResult{result.Id, result.Revision})
+	}
+	return &result, nil
+}
+
+// UpsertRecords is like UpsertRecord but upserts up to 100 records at
+// once.  Results are in the order of recs.
+func (app *App) UpsertRecords(recs []*Record, ignoreRevision bool, keyField string) ([]UpsertResult, error) {
+	return app.UpsertRecordsContext(context.Background(), recs, ignoreRevision, keyField)
+}
+
+// UpsertRecordsContext is like UpsertRecords but uses ctx for the API requests.
+func (app *App) UpsertRecordsContext(ctx context.Context, recs []*Record, ignoreRevision bool, keyField string) ([]UpsertResult, error) {
+	if len(recs) > 100 {
+		return nil, ErrTooMany
+	}
+	if err := app.checkKeyField(ctx, recs, keyField); err != nil {
+		return nil, err
+	}
+	req, err := app.updateRecordsByKeyRequest(app.AppId, recs, ignoreRevision, keyField, true)
+	if err != nil {
+		return nil, err
+	}
+	body, err := app.call(ctx, "PUT", "records", req)
+	if err != nil {
+		app.forgetKeyField(keyField)
+		return nil, err
+	}
+
+	var t struct {
+		Records []upsertResult `json:"records"`
+	}
+	if json.Unmarshal(body, &t) != nil || len(t.Records) != len(recs) {
+		return nil, ErrInvalidResponse
+	}
+	results := make([]UpsertResult, len(recs))
+	for i, r := range t.Records {
+		if results[i], err = r.result(); err != nil {
+			return nil, err
+		}
+	}
+	for i, rec := range recs {
+		rec.takeSnapshot()
+		if app.SyncRecords {
+			syncRecord(rec, RecordResult{results[i].Id, results[i].Revision})
+		}
+	}
+	return results, nil
+}
+
+// keyFieldTTL is how long a valid key field of upserts is remembered.
+const keyFieldTTL = 10 * time.Minute
+
+// keyCache remembers key fields which passed checkKeyField.  It is
+// created by NewApp and NewClient, and shared by the apps of a Client.
+// A nil cache remembers nothing.
+type keyCache struct {
+	mu      sync.Mutex
+	expires map[keyFieldKey]time.Time
+	now     func() time.Time // for testing
+}
+
+// keyFieldKey identifies a key field by the URL of the fields API, which
+// differs by domain and guest space.
+type keyFieldKey struct {
+	url      string
+	appId    uint64
+	keyField string
+}
+
+func newKeyCache() *keyCache {
+	return &keyCache{expires: make(map[keyFieldKey]time.Time), now: time.Now}
+}
+
+func (c *keyCache) valid(key keyFieldKey) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	exp, ok := c.expires[key]
+	if ok && !c.now().Before(exp) {
+		delete(c.expires, key)
+		return false
+	}
+	return ok
+}
+
+func (c *keyCache) store(key keyFieldKey) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expires[key] = c.now().Add(keyFieldTTL)
+}
+
+func (c *keyCache) forget(key keyFieldKey) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.expires, key)
+}
+
+// keyFieldKey returns the cache key of keyField of app.
+func (app *App) keyFieldKey(keyField string) (keyFieldKey, error) {
+	u, err := app.apiURL("app/form/fields", "")
+	if err != nil {
+		return keyFieldKey{}, err
+	}
+	return keyFieldKey{u.String(), app.AppId, keyField}, nil
+}
+
+// forgetKeyField makes keyField checked again by the next upsert.
+func (app *App) forgetKeyField(keyField string) {
+	if key, err := app.keyFieldKey(keyField); err == nil {
+		app.keyFields.forget(key)
+	}
+}
+
+// checkKeyField returns ErrInvalidKeyField unless keyField is a unique
+// field which can identify records in upserts and every record has it.
+func (app *App) checkKeyField(ctx context.Context, recs []*Record, keyField string) error {
+	for _, rec := range recs {
+		if _, ok := rec.Fields[keyField].(UpdateKeyField); !ok {
+			return fmt.Errorf("%w: record has no %s", ErrInvalidKeyField, keyField)
+		}
+	}
+	key, err := app.keyFieldKey(keyField)
+	if err != nil {
+		return err
+	}
+	if app.keyFields.valid(key) {
+		return nil
+	}
+
+	fields, err := app.FieldsContext(ctx)
+	if err != nil {
+		return err
+	}
+	fi, ok := fields[keyField]
+	if !ok {
+		return fmt.Errorf("%w: no such field: %s", ErrInvalidKeyField, keyField)
+	}
+	if fi.Type != FT_SINGLE_LINE_TEXT && fi.Type != FT_DECIMAL {
+		return fmt.Errorf("%w: %s is %s", ErrInvalidKeyField, keyField, fi.Type)
+	}
+	if !fi.Unique {
+		return fmt.Errorf("%w: %s allows duplicate values", ErrInvalidKeyField, keyField)
+	}
+	app.keyFields.store(key)
+	return nil
+}
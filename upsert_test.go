@@ -0,0 +1,142 @@
+// (C) 2014 Cybozu.  All rights reserved.
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file.
+
+package kintone
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newUpsertTestApp returns an app which has the fields of
+// GetDataTestFormFields.  Bodies of PUT requests are recorded in puts.
+// The app is created by NewApp, so that it remembers valid key fields.
+// The title "bad" fails to be upserted.
+func newUpsertTestApp(t *testing.T) (*App, *requestLog, *int32) {
+	puts := &requestLog{}
+	var fieldsCalls int32
+	app := newTestApp(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.Replace(r.URL.Path, "/k/guest/3/", "/k/", 1) {
+		case "/k/v1/app/form/fields.json":
+			atomic.AddInt32(&fieldsCalls, 1)
+			respond(http.StatusOK, GetDataTestFormFields().output)(w, r)
+		case "/k/v1/record.json":
+			if strings.Contains(string(puts.addBody(r)), `"bad"`) {
+				respond(http.StatusBadRequest, GetTestDataInvalidValue().output)(w, r)
+				return
+			}
+			respond(http.StatusOK, GetTestDataUpsertRecord().output)(w, r)
+		case "/k/v1/records.json":
+			puts.addBody(r)
+			respond(http.StatusOK, GetTestDataUpsertRecords().output)(w, r)
+		}
+	}))
+	app, err := NewApp(*app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app, puts, &fieldsCalls
+}
+
+func TestUpsertRecord(t *testing.T) {
+	app, puts, _ := newUpsertTestApp(t)
+	rec := NewRecord(map[string]interface{}{
+		"string_1": SingleLineTextField("A-1"),
+		"number_1": DecimalField("5"),
+	})
+	result, err := app.UpsertRecord(rec, true, "string_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *result != (UpsertResult{8, 1, true}) {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	expected := `{"app":"1","updateKey":{"field":"string_1","value":"A-1"},"revision":"-1","record":{"number_1":{"type":"NUMBER","value":"5"}},"upsert":true}`
+	if got := puts.all(); len(got) != 1 || got[0] != expected {
+		t.Errorf("unexpected request: %v", got)
+	}
+
+	for _, keyField := range []string{"number_1", "checkbox_1", "missing"} {
+		rec := NewRecord(map[string]interface{}{keyField: DecimalField("1")})
+		if _, err := app.UpsertRecord(rec, true, keyField); !errors.Is(err, ErrInvalidKeyField) {
+			t.Errorf("%s: unexpected error: %v", keyField, err)
+		}
+	}
+	if _, err := app.UpsertRecord(NewRecord(nil), true, "string_1"); !errors.Is(err, ErrInvalidKeyField) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := puts.all(); len(got) != 1 {
+		t.Errorf("invalid keys must not be sent: %v", got)
+	}
+}
+
+func TestUpsertKeyFieldCache(t *testing.T) {
+	app, _, fieldsCalls := newUpsertTestApp(t)
+	now := time.Now()
+	app.keyFields.now = func() time.Time { return now }
+	upsert := func(app *App, title string, checked bool) {
+		t.Helper()
+		calls := atomic.LoadInt32(fieldsCalls)
+		rec := NewRecord(map[string]interface{}{"string_1": SingleLineTextField(title)})
+		app.UpsertRecord(rec, true, "string_1")
+		if got := atomic.LoadInt32(fieldsCalls) != calls; got != checked {
+			t.Errorf("unexpected check of the key field: %v", got)
+		}
+	}
+	upsert(app, "A-1", true)
+	upsert(app, "A-1", false)
+	copied := *app
+	upsert(&copied, "A-1", false)
+
+	now = now.Add(keyFieldTTL)
+	upsert(app, "A-1", true)
+	upsert(app, "bad", false)
+	upsert(app, "A-1", true)
+
+	// Apps built as struct literals check every time.
+	literal := &App{BaseURL: app.BaseURL, User: app.User, Password: app.Password, AppId: app.AppId}
+	upsert(literal, "A-1", true)
+	upsert(literal, "A-1", true)
+
+	// The apps of a Client share their cache, which other Clients do not.
+	for i := 0; i < 2; i++ {
+		c, err := NewClient(Client{BaseURL: app.BaseURL, User: app.User, Password: app.Password})
+		if err != nil {
+			t.Fatal(err)
+		}
+		upsert(c.App(app.AppId), "A-1", true)
+		upsert(c.App(app.AppId), "A-1", false)
+		upsert(c.GuestSpace(3).App(app.AppId), "A-1", true)
+	}
+}
+
+func TestUpsertRecords(t *testing.T) {
+	app, puts, _ := newUpsertTestApp(t)
+	recs := []*Record{
+		NewRecordWithIdAndRevision(3, 4, map[string]interface{}{"string_1": SingleLineTextField("A-1")}),
+		NewRecord(map[string]interface{}{"string_1": SingleLineTextField("A-2")}),
+	}
+	results, err := app.UpsertRecords(recs, false, "string_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, []UpsertResult{{3, 5, false}, {9, 1, true}}) {
+		t.Errorf("unexpected results: %v", results)
+	}
+	expected := `{"app":"1","records":[` +
+		`{"updateKey":{"field":"string_1","value":"A-1"},"revision":"4","record":{}},` +
+		`{"updateKey":{"field":"string_1","value":"A-2"},"revision":"-1","record":{}}],"upsert":true}`
+	if got := puts.all(); len(got) != 1 || got[0] != expected {
+		t.Errorf("unexpected request: %v", got)
+	}
+
+	if _, err := app.UpsertRecords(make([]*Record, 101), false, "string_1"); err != ErrTooMany {
+		t.Errorf("unexpected error: %v", err)
+	}
+}